@@ -0,0 +1,69 @@
+package common
+
+import "math/rand"
+
+func (rules Rules) Upkeep() bool {
+	return rules.BeeUpkeep > 0 && rules.UpkeepInterval > 0
+}
+
+func (gs *GameState) countUnits(kind EntityType) []uint {
+	counts := make([]uint, gs.NumPlayers)
+	for _, hex := range gs.Hexes {
+		if hex.Entity != nil && hex.Entity.Type == kind {
+			counts[hex.Entity.Player]++
+		}
+	}
+	return counts
+}
+
+// spawnCost gives the price of the next bee of a player, which grows with the
+// number of bees the player already has in games that scale it
+func (gs *GameState) spawnCost(bees uint) uint {
+	return gs.Rules.BeeCost + gs.Rules.SpawnCostScale*bees
+}
+
+// canSupportBee tells whether the hives of a player can support one more bee
+func (gs *GameState) canSupportBee(player int) bool {
+	if gs.Rules.HiveCapacity == 0 {
+		return true
+	}
+	bees := gs.countUnits(BEE)[player]
+	hives := gs.countUnits(HIVE)[player]
+	return bees < hives*gs.Rules.HiveCapacity
+}
+
+// payUpkeep takes the upkeep of their bees from the players' resources. Bees
+// that cannot be paid for starve: random ones are removed from the map.
+func (gs *GameState) payUpkeep() {
+	if !gs.Rules.Upkeep() || gs.Turn%gs.Rules.UpkeepInterval != 0 {
+		return
+	}
+
+	bees := make([][]Coords, gs.NumPlayers)
+	for coords, hex := range gs.Hexes {
+		if hex.Entity != nil && hex.Entity.Type == BEE {
+			bees[hex.Entity.Player] = append(bees[hex.Entity.Player], coords)
+		}
+	}
+
+	for player, coords := range bees {
+		paid := min(uint(len(coords)), gs.PlayerResources[player]/gs.Rules.BeeUpkeep)
+		gs.PlayerResources[player] -= paid * gs.Rules.BeeUpkeep
+
+		rand.Shuffle(len(coords), func(i, j int) {
+			coords[i], coords[j] = coords[j], coords[i]
+		})
+		for _, c := range coords[paid:] {
+			gs.killBee(c)
+		}
+	}
+}
+
+// updateEconomy refreshes the bee counts and spawn costs given to the players
+func (gs *GameState) updateEconomy() {
+	gs.PlayerBees = gs.countUnits(BEE)
+	gs.SpawnCosts = make([]uint, gs.NumPlayers)
+	for player, bees := range gs.PlayerBees {
+		gs.SpawnCosts[player] = gs.spawnCost(bees)
+	}
+}
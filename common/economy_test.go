@@ -0,0 +1,99 @@
+package common
+
+import "testing"
+
+func TestUpkeepStarvation(t *testing.T) {
+	gs := testState(t, 2)
+	gs.Rules.BeeUpkeep = 2
+	gs.Rules.UpkeepInterval = 1
+
+	// Enough for all the bees of player 0 but one
+	bees := gs.PlayerBees[0]
+	gs.PlayerResources[0] = 2*(bees-1) + 1
+
+	gs.ProcessOrders([][]*Order{nil, nil})
+
+	if gs.PlayerBees[0] != bees-1 || gs.PlayerResources[0] != 1 {
+		t.Errorf("%d bees and %d flowers left, expected %d and 1", gs.PlayerBees[0], gs.PlayerResources[0], bees-1)
+	}
+	if gs.PlayerBees[1] != 0 {
+		t.Errorf("%d bees left to player 1, who could not pay for any", gs.PlayerBees[1])
+	}
+}
+
+func TestUpkeepInterval(t *testing.T) {
+	gs := testState(t, 2)
+	gs.Rules.BeeUpkeep = 1
+	gs.Rules.UpkeepInterval = 3
+	gs.PlayerResources[0] = 100
+
+	bees := gs.PlayerBees[0]
+	for turn := 1; turn <= 6; turn++ {
+		gs.ProcessOrders([][]*Order{nil, nil})
+
+		paid := 100 - gs.PlayerResources[0]
+		if expected := uint(turn/3) * bees; paid != expected {
+			t.Errorf("turn %d: %d flowers paid, expected %d", turn, paid, expected)
+		}
+	}
+}
+
+func TestDefaultUpkeepEveryTurn(t *testing.T) {
+	gs := testState(t, 2)
+	gs.Rules.BeeUpkeep = 1
+	gs.PlayerResources[0] = 100
+
+	bees := gs.PlayerBees[0]
+	gs.ProcessOrders([][]*Order{nil, nil})
+
+	if gs.PlayerResources[0] != 100-bees {
+		t.Errorf("%d flowers left after the first turn, expected %d", gs.PlayerResources[0], 100-bees)
+	}
+}
+
+func TestHiveCapacity(t *testing.T) {
+	gs := testState(t, 2)
+	gs.Rules.HiveCapacity = gs.PlayerBees[0]
+	gs.PlayerResources[0] = 100
+
+	hive, dir := firstUnit(t, gs, 0, HIVE)
+	results, _ := gs.ProcessOrders([][]*Order{{{Type: SPAWN, Coords: hive, Direction: dir}}, nil})
+
+	if results[0].Status != CAPACITY_REACHED || gs.PlayerResources[0] != 100 {
+		t.Errorf("spawn status %s, %d flowers left", results[0].Status, gs.PlayerResources[0])
+	}
+
+	gs.Rules.HiveCapacity++
+	results, _ = gs.ProcessOrders([][]*Order{{{Type: SPAWN, Coords: hive, Direction: dir}}, nil})
+
+	if results[0].Status != OK {
+		t.Errorf("spawn status %s with room for one more bee", results[0].Status)
+	}
+}
+
+func TestSpawnCostScale(t *testing.T) {
+	mapData, err := LoadMap("../maps/balanced.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapData.Rules.SpawnCostScale = 2
+	gs := NewGameState(mapData, 2)
+
+	bees := gs.PlayerBees[0]
+	cost := gs.Rules.BeeCost + 2*bees
+	if gs.SpawnCosts[0] != cost {
+		t.Fatalf("first spawn costs %d, expected %d", gs.SpawnCosts[0], cost)
+	}
+
+	// Exactly enough for one bee
+	gs.PlayerResources[0] = cost
+	hive, dir := firstUnit(t, gs, 0, HIVE)
+	results, _ := gs.ProcessOrders([][]*Order{{{Type: SPAWN, Coords: hive, Direction: dir}}, nil})
+
+	if results[0].Status != OK || gs.PlayerResources[0] != 0 {
+		t.Errorf("spawn status %s, %d flowers left", results[0].Status, gs.PlayerResources[0])
+	}
+	if gs.PlayerBees[0] != bees+1 || gs.SpawnCosts[0] != cost+2 {
+		t.Errorf("%d bees, next one costs %d, expected %d and %d", gs.PlayerBees[0], gs.SpawnCosts[0], bees+1, cost+2)
+	}
+}
@@ -12,15 +12,33 @@ const (
 	HIVE_COST          = 12
 	WALL_COST          = 1
 	WALL_ATTACK_CHANCE = 1.0 / 6.0
+	HIVE_HEALTH        = 12
+	BEE_HEALTH         = 3
 	STUN_CHANCE        = 1.0 / 2.0
-	FIELD_OF_VIEW      = 4
+	STUN_TURNS         = 0
+	HIVE_SIGHT         = 4
+	BEE_SIGHT          = 4
+	WALL_SIGHT         = 4
 	RESOURCE_TIMEOUT   = 50
+	UPKEEP_INTERVAL    = 1
+	MAX_PLAYERS        = 6
 )
 
 type Entity struct {
 	Type      EntityType `json:"type"`
 	Player    int        `json:"player"`
 	HasFlower bool       `json:"hasFlower,omitzero"`
+	Health    uint       `json:"health,omitzero"`
+
+	// First turn during which a stunned entity can act again
+	StunnedUntil uint `json:"stunnedUntil,omitzero"`
+
+	// Turn at the start of which a decaying wall disappears
+	ExpiresAt uint `json:"expiresAt,omitzero"`
+}
+
+func (e *Entity) IsStunned(turn uint) bool {
+	return turn < e.StunnedUntil
 }
 
 type EntityType string
@@ -34,7 +52,14 @@ const (
 type Hex struct {
 	Terrain   Terrain `json:"terrain"`
 	Resources uint    `json:"resources,omitzero"`
+	Capacity  uint    `json:"capacity,omitzero"`
+	Hill      bool    `json:"hill,omitzero"`
+	Flowers   uint    `json:"flowers,omitzero"`
 	Entity    *Entity `json:"entity,omitempty"`
+
+	// A wall that the bee in the hex stands in, in games where bees can pass
+	// through the walls of their player
+	Wall *Entity `json:"wall,omitempty"`
 }
 
 type Order struct {
@@ -54,6 +79,9 @@ const (
 	BUILD_HIVE OrderType = "BUILD_HIVE"
 	FORAGE     OrderType = "FORAGE"
 	SPAWN      OrderType = "SPAWN"
+	DROP       OrderType = "DROP"
+	PICKUP     OrderType = "PICKUP"
+	DISMANTLE  OrderType = "DISMANTLE"
 )
 
 type OrderStatus string
@@ -64,10 +92,24 @@ const (
 	BLOCKED              OrderStatus = "BLOCKED"
 	INVALID_TARGET       OrderStatus = "INVALID_TARGET"
 	CANNOT_FORAGE        OrderStatus = "CANNOT_FORAGE"
+	CANNOT_DROP          OrderStatus = "CANNOT_DROP"
+	CANNOT_PICKUP        OrderStatus = "CANNOT_PICKUP"
 	NOT_ENOUGH_RESOURCES OrderStatus = "NOT_ENOUGH_RESOURCES"
 	UNIT_ALREADY_ACTED   OrderStatus = "UNIT_ALREADY_ACTED"
 	UNIT_STUNNED         OrderStatus = "UNIT_STUNNED"
+	COLLISION            OrderStatus = "COLLISION"
+	NOT_ALLOWED          OrderStatus = "NOT_ALLOWED"
+	NOT_OWNER            OrderStatus = "NOT_OWNER"
+	CAPACITY_REACHED     OrderStatus = "CAPACITY_REACHED"
 	OK                   OrderStatus = "OK"
+
+	// Outcomes of successful attacks
+
+	MISSED    OrderStatus = "MISSED"
+	DAMAGED   OrderStatus = "DAMAGED"
+	STUNNED   OrderStatus = "STUNNED"
+	KILLED    OrderStatus = "KILLED"
+	DESTROYED OrderStatus = "DESTROYED"
 )
 
 func (o *Order) UnitType() EntityType {
@@ -88,10 +130,18 @@ type GameState struct {
 	PlayerResources    []uint          `json:"playerResources"`
 	LastResourceChange uint            `json:"lastResourceChange"`
 
-	Winners  []int `json:"winners,omitempty"`
-	GameOver bool  `json:"gameOver"`
+	Winners   []int     `json:"winners,omitempty"`
+	GameOver  bool      `json:"gameOver"`
+	EndReason EndReason `json:"endReason,omitempty"`
 
-	stunned map[*Entity]bool
+	HillPoints []uint `json:"hillPoints,omitempty"`
+	Eliminated []bool `json:"eliminated"`
+
+	// Number of bees of each player, and the cost of their next bee
+	PlayerBees []uint `json:"playerBees"`
+	SpawnCosts []uint `json:"spawnCosts"`
+
+	Rules Rules `json:"rules"`
 }
 
 var playerMappings = [][]int{
@@ -104,51 +154,118 @@ var playerMappings = [][]int{
 	{0, 1, 2, 3, 4, 5},
 }
 
+// PlayerMapping gives, for each spawn slot of the map, the player it belongs to
+// in a game with the given number of players, or -1 if the slot is unused.
+// Maps can give their own assignments for some player counts.
+func (m MapData) PlayerMapping(numPlayers int) []int {
+	slots, found := m.Slots[numPlayers]
+	if !found {
+		return playerMappings[numPlayers]
+	}
+
+	mapping := slices.Repeat([]int{-1}, MAX_PLAYERS)
+	for player, slot := range slots {
+		if slot >= 0 && slot < MAX_PLAYERS {
+			mapping[slot] = player
+		}
+	}
+	return mapping
+}
+
+func (m MapData) SupportsNumPlayers(n int) bool {
+	return IsValidNumPlayers(n) && (len(m.Players) == 0 || slices.Contains(m.Players, n))
+}
+
+func (m MapData) InitialFlowers(coords Coords) uint {
+	if flowers, found := m.Flowers[coords]; found {
+		return flowers
+	}
+	return m.Rules.InitFieldFlowers
+}
+
 func IsValidNumPlayers(n int) bool {
-	return n >= 1 && n <= 6
+	return n >= 1 && n <= MAX_PLAYERS
 }
 
 func NewGameState(mapData MapData, numPlayers int) *GameState {
 
-	if !IsValidNumPlayers(numPlayers) {
+	if !mapData.SupportsNumPlayers(numPlayers) {
 		return nil
 	}
 
 	gs := &GameState{
 		NumPlayers: numPlayers,
 		Hexes:      make(map[Coords]*Hex),
+		Rules:      mapData.Rules,
 	}
 
 	for coords, terrain := range mapData.Map {
 		gs.Hexes[coords] = &Hex{Terrain: terrain}
 	}
 
+	mapping := mapData.PlayerMapping(numPlayers)
 	for _, spawn := range mapData.Spawns {
-		player := playerMappings[numPlayers][spawn.Player]
+		hex := gs.Hexes[spawn.Coords]
+		if hex == nil || spawn.Player < 0 || spawn.Player >= MAX_PLAYERS {
+			continue
+		}
+
+		player := mapping[spawn.Player]
 		if player == -1 {
 			continue
 		}
 
 		switch spawn.Kind {
-		case HIVE:
-			gs.Hexes[spawn.Coords].Entity = &Entity{Type: HIVE, Player: player}
-		case BEE:
-			gs.Hexes[spawn.Coords].Entity = &Entity{Type: BEE, Player: player}
+		case HIVE, BEE:
+			hex.Entity = gs.newEntity(spawn.Kind, player)
 		}
 	}
 
-	for _, hex := range gs.Hexes {
+	for coords, hex := range gs.Hexes {
 		if hex.Terrain == FIELD {
-			hex.Resources = INIT_FIELD_FLOWERS
+			hex.Resources = mapData.InitialFlowers(coords)
+
+			if gs.Rules.Regrowth() {
+				hex.Capacity = hex.Resources
+				if gs.Rules.RegrowthCap > 0 {
+					hex.Capacity = gs.Rules.RegrowthCap
+				}
+			}
+		}
+	}
+
+	for _, coords := range mapData.Hills {
+		if hex := gs.Hexes[coords]; hex != nil {
+			hex.Hill = true
 		}
 	}
 
 	gs.PlayerResources = make([]uint, numPlayers)
+	gs.Eliminated = make([]bool, numPlayers)
+	if gs.Rules.Victory == KING_OF_THE_HILL {
+		gs.HillPoints = make([]uint, numPlayers)
+	}
+	gs.updateEconomy()
 	gs.checkEndGame()
 
 	return gs
 }
 
+func (gs *GameState) newEntity(kind EntityType, player int) *Entity {
+	entity := &Entity{Type: kind, Player: player}
+	switch kind {
+	case HIVE:
+		entity.Health = gs.Rules.HiveHealth
+	case BEE:
+		entity.Health = gs.Rules.BeeHealth
+	case WALL:
+		if gs.Rules.WallDecay > 0 {
+			entity.ExpiresAt = gs.Turn + 1 + gs.Rules.WallDecay
+		}
+	}
+	return entity
+}
+
 func (gs *GameState) EntityAt(coords Coords) *Entity {
 	hex, ok := gs.Hexes[coords]
 	if !ok {
@@ -180,7 +297,6 @@ func (gs *GameState) ProcessOrders(orders [][]*Order) ([]*Order, error) {
 	}
 
 	acted := make(map[*Entity]bool)
-	gs.stunned = make(map[*Entity]bool)
 	var processed []*Order
 
 	// Process round by round
@@ -188,9 +304,12 @@ func (gs *GameState) ProcessOrders(orders [][]*Order) ([]*Order, error) {
 	for roundNumber := range numRounds {
 		roundOrders := []*Order{}
 
-		// Gather orders for this round
+		// Gather orders for this round, ignoring eliminated players
 
-		for _, playerOrders := range orders {
+		for player, playerOrders := range orders {
+			if gs.IsEliminated(player) {
+				continue
+			}
 			if roundNumber < len(playerOrders) {
 				roundOrders = append(roundOrders, playerOrders[roundNumber])
 			}
@@ -202,21 +321,29 @@ func (gs *GameState) ProcessOrders(orders [][]*Order) ([]*Order, error) {
 			roundOrders[i], roundOrders[j] = roundOrders[j], roundOrders[i]
 		})
 
+		// Resolve the moves all at once if the rules ask for it
+
+		if gs.Rules.MoveResolution == SIMULTANEOUS {
+			var moves, others []*Order
+			for _, order := range roundOrders {
+				if order.Type == MOVE {
+					moves = append(moves, order)
+				} else {
+					others = append(others, order)
+				}
+			}
+
+			processed = append(processed, moves...)
+			gs.resolveMoves(moves, acted)
+			roundOrders = others
+		}
+
 		// Apply them
 
 		for _, order := range roundOrders {
 			processed = append(processed, order)
-			unit := gs.EntityAt(order.Coords)
-			if unit == nil {
-				order.Status = INVALID_UNIT
-				continue
-			} else if acted[unit] {
-				order.Status = UNIT_ALREADY_ACTED
-				continue
-			} else if gs.stunned[unit] {
-				order.Status = UNIT_STUNNED
-				continue
-			} else {
+			unit := gs.unitCanAct(order, acted)
+			if unit != nil {
 				gs.applyOrder(order)
 				acted[unit] = true
 			}
@@ -224,11 +351,141 @@ func (gs *GameState) ProcessOrders(orders [][]*Order) ([]*Order, error) {
 	}
 
 	gs.Turn++
+	gs.clearStuns()
+	gs.decayWalls()
+	gs.payUpkeep()
+	gs.checkEliminations()
+	gs.regrowFields()
+	gs.scoreHills()
+	gs.updateEconomy()
 	gs.checkEndGame()
 
 	return processed, nil
 }
 
+func (gs *GameState) unitCanAct(order *Order, acted map[*Entity]bool) *Entity {
+	unit := gs.EntityAt(order.Coords)
+	if unit == nil {
+		order.Status = INVALID_UNIT
+		return nil
+	} else if acted[unit] {
+		order.Status = UNIT_ALREADY_ACTED
+		return nil
+	} else if unit.IsStunned(gs.Turn) {
+		order.Status = UNIT_STUNNED
+		return nil
+	}
+	return unit
+}
+
+// resolveMoves applies the move orders of a round simultaneously. Bees moving
+// into the same hex all bounce back, and bees can move into hexes left by
+// other bees in the same round, including in chains and rotations.
+func (gs *GameState) resolveMoves(orders []*Order, acted map[*Entity]bool) {
+	moving := make(map[Coords]*Order)
+	destinations := make(map[Coords]Coords)
+
+	for _, order := range orders {
+		unit := gs.unitCanAct(order, acted)
+		if unit == nil {
+			continue
+		}
+		acted[unit] = true
+
+		if gs.getUnit(order) == nil {
+			continue
+		}
+
+		destination := order.Target()
+		hex := gs.Hexes[destination]
+		if hex == nil || !hex.Terrain.IsWalkable() {
+			order.Status = BLOCKED
+			continue
+		}
+
+		moving[order.Coords] = order
+		destinations[order.Coords] = destination
+	}
+
+	// Drop failing moves until all the remaining ones can happen together
+
+	for changed := true; changed; {
+		changed = false
+
+		arrivals := make(map[Coords]int)
+		for coords := range moving {
+			arrivals[destinations[coords]]++
+		}
+
+		for coords, order := range moving {
+			destination := destinations[coords]
+			_, leaving := moving[destination]
+
+			// A bee leaving a hex leaves the wall it stood in, if any
+			occupant := gs.Hexes[destination].Entity
+			if leaving {
+				occupant = gs.Hexes[destination].Wall
+			}
+
+			if arrivals[destination] > 1 {
+				order.Status = COLLISION
+			} else if occupant != nil && !gs.canPassThrough(occupant, order.Player) {
+				order.Status = BLOCKED
+			} else {
+				continue
+			}
+
+			delete(moving, coords)
+			changed = true
+		}
+	}
+
+	bees := make(map[Coords]*Entity)
+	for coords := range moving {
+		bees[coords] = gs.leaveHex(coords)
+	}
+
+	for coords, order := range moving {
+		gs.enterHex(destinations[coords], bees[coords])
+		order.Status = OK
+	}
+}
+
+func (gs *GameState) clearStuns() {
+	for _, hex := range gs.Hexes {
+		if hex.Entity != nil && !hex.Entity.IsStunned(gs.Turn) {
+			hex.Entity.StunnedUntil = 0
+		}
+	}
+}
+
+func (gs *GameState) decayWalls() {
+	expired := func(wall *Entity) bool {
+		return wall != nil && wall.ExpiresAt > 0 && gs.Turn >= wall.ExpiresAt
+	}
+
+	for _, hex := range gs.Hexes {
+		if expired(hex.Entity) {
+			hex.Entity = nil
+		}
+		if expired(hex.Wall) {
+			hex.Wall = nil
+		}
+	}
+}
+
+func (gs *GameState) regrowFields() {
+	if !gs.Rules.Regrowth() || gs.Turn%gs.Rules.RegrowthInterval != 0 {
+		return
+	}
+
+	for _, hex := range gs.Hexes {
+		if hex.Terrain == FIELD && hex.Resources < hex.Capacity {
+			hex.Resources++
+		}
+	}
+}
+
 func (gs *GameState) applyOrder(order *Order) {
 	switch order.Type {
 	case MOVE:
@@ -243,6 +500,12 @@ func (gs *GameState) applyOrder(order *Order) {
 		gs.applyForageOrder(order)
 	case SPAWN:
 		gs.applySpawnOrder(order)
+	case DROP:
+		gs.applyDropOrder(order)
+	case PICKUP:
+		gs.applyPickupOrder(order)
+	case DISMANTLE:
+		gs.applyDismantleOrder(order)
 	}
 }
 
@@ -256,8 +519,11 @@ func (gs *GameState) getUnit(order *Order) *Entity {
 }
 
 func (gs *GameState) TargetIsBlocked(order *Order) bool {
+	return gs.isBlocked(order.Target(), order)
+}
 
-	hex := gs.Hexes[order.Target()]
+func (gs *GameState) isBlocked(coords Coords, order *Order) bool {
+	hex := gs.Hexes[coords]
 	if hex == nil || !hex.Terrain.IsWalkable() || hex.Entity != nil {
 		order.Status = BLOCKED
 		return true
@@ -279,16 +545,43 @@ func (gs *GameState) applyMoveOrder(order *Order) {
 	if bee == nil {
 		return
 	}
-	if gs.TargetIsBlocked(order) {
+	target := gs.Hexes[order.Target()]
+	passing := target != nil && target.Entity != nil && gs.canPassThrough(target.Entity, order.Player)
+	if !passing && gs.TargetIsBlocked(order) {
 		return
 	}
 
-	gs.Hexes[order.Coords].Entity = nil
-	gs.Hexes[order.Target()].Entity = bee
+	gs.leaveHex(order.Coords)
+	gs.enterHex(order.Target(), bee)
 
 	order.Status = OK
 }
 
+// canPassThrough tells whether a bee of the player can move into a hex holding
+// the given entity: only walls of its own player, in games that allow it
+func (gs *GameState) canPassThrough(entity *Entity, player int) bool {
+	return gs.Rules.PassThroughWalls && entity.Type == WALL && entity.Player == player
+}
+
+// leaveHex takes a bee out of its hex, leaving behind the wall it stood in,
+// if any
+func (gs *GameState) leaveHex(coords Coords) *Entity {
+	hex := gs.Hexes[coords]
+	bee := hex.Entity
+	hex.Entity, hex.Wall = hex.Wall, nil
+	return bee
+}
+
+// enterHex puts a bee in a hex, which is either free or holds a wall the bee
+// can pass through
+func (gs *GameState) enterHex(coords Coords, bee *Entity) {
+	hex := gs.Hexes[coords]
+	if hex.Entity != nil {
+		hex.Wall = hex.Entity
+	}
+	hex.Entity = bee
+}
+
 func (gs *GameState) applyAttackOrder(order *Order) {
 	if gs.getUnit(order) == nil {
 		return
@@ -300,15 +593,59 @@ func (gs *GameState) applyAttackOrder(order *Order) {
 		return
 	}
 
-	if entity.Type == WALL && rand.Float64() < WALL_ATTACK_CHANCE {
-		gs.Hexes[order.Target()].Entity = nil
-	}
+	hex := gs.Hexes[order.Target()]
+	order.Status = MISSED
+
+	switch entity.Type {
+	case WALL:
+		if rand.Float64() < gs.Rules.WallAttackChance {
+			hex.Entity = nil
+			order.Status = DESTROYED
+		}
+
+	case HIVE:
+		// Hives without health cannot be destroyed
+		if entity.Health == 0 {
+			break
+		}
+
+		entity.Health--
+		order.Status = DAMAGED
+
+		if entity.Health == 0 {
+			hex.Entity = nil
+			order.Status = DESTROYED
+		}
+
+	case BEE:
+		// Same for bees, which can still be stunned
+		if entity.Health > 0 {
+			entity.Health--
+			order.Status = DAMAGED
 
-	if entity.Type == BEE && rand.Float64() < STUN_CHANCE {
-		gs.stunned[entity] = true
+			if entity.Health == 0 {
+				gs.killBee(order.Target())
+				order.Status = KILLED
+				return
+			}
+		}
+
+		if rand.Float64() < gs.Rules.StunChance {
+			entity.StunnedUntil = gs.Turn + 1 + gs.Rules.StunTurns
+			order.Status = STUNNED
+		}
 	}
+}
 
-	order.Status = OK
+// killBee removes a bee from the map. The flower it carried, if any, is left
+// on the ground where it died, for any bee to pick up, and the wall it stood
+// in, if any, stays in place.
+func (gs *GameState) killBee(coords Coords) {
+	hex := gs.Hexes[coords]
+	if hex.Entity.HasFlower {
+		hex.Flowers++
+	}
+	hex.Entity, hex.Wall = hex.Wall, nil
 }
 
 func (gs *GameState) applyBuildWallOrder(order *Order) {
@@ -318,25 +655,54 @@ func (gs *GameState) applyBuildWallOrder(order *Order) {
 	if gs.TargetIsBlocked(order) {
 		return
 	}
-	if !gs.tryToPay(order, WALL_COST) {
+	if !gs.tryToPay(order, gs.Rules.WallCost) {
 		return
 	}
 
-	wall := &Entity{Type: WALL, Player: order.Player}
+	wall := gs.newEntity(WALL, order.Player)
 	gs.Hexes[order.Target()].Entity = wall
 
 	order.Status = OK
 }
 
+func (gs *GameState) applyDismantleOrder(order *Order) {
+	if gs.getUnit(order) == nil {
+		return
+	}
+	if !gs.Rules.DismantleWalls {
+		order.Status = NOT_ALLOWED
+		return
+	}
+
+	wall := gs.EntityAt(order.Target())
+	if wall == nil || wall.Type != WALL {
+		order.Status = INVALID_TARGET
+		return
+	}
+	if wall.Player != order.Player {
+		order.Status = NOT_OWNER
+		return
+	}
+
+	gs.Hexes[order.Target()].Entity = nil
+	gs.PlayerResources[order.Player] += gs.Rules.WallRefund
+
+	order.Status = OK
+}
+
 func (gs *GameState) applyBuildHiveOrder(order *Order) {
 	if gs.getUnit(order) == nil {
 		return
 	}
-	if !gs.tryToPay(order, HIVE_COST) {
+	if gs.Hexes[order.Coords].Wall != nil {
+		order.Status = BLOCKED
+		return
+	}
+	if !gs.tryToPay(order, gs.Rules.HiveCost) {
 		return
 	}
 
-	hive := &Entity{Type: HIVE, Player: order.Player}
+	hive := gs.newEntity(HIVE, order.Player)
 	gs.Hexes[order.Coords].Entity = hive
 
 	order.Status = OK
@@ -379,69 +745,67 @@ func (gs *GameState) applyForageOrder(order *Order) {
 	}
 }
 
-func (gs *GameState) applySpawnOrder(order *Order) {
-	if gs.getUnit(order) == nil {
-		return
-	}
-	if gs.TargetIsBlocked(order) {
+func (gs *GameState) applyDropOrder(order *Order) {
+	bee := gs.getUnit(order)
+	if bee == nil {
 		return
 	}
-	if !gs.tryToPay(order, BEE_COST) {
+
+	if !bee.HasFlower {
+		order.Status = CANNOT_DROP
 		return
 	}
 
-	bee := &Entity{Type: BEE, Player: order.Player}
-	gs.Hexes[order.Target()].Entity = bee
+	bee.HasFlower = false
+	gs.Hexes[order.Coords].Flowers++
 
 	order.Status = OK
 }
 
-func (gs *GameState) checkEndGame() {
-
-	// No resources left
-
-	var resourcesLeft uint
-	for _, hex := range gs.Hexes {
-		resourcesLeft += hex.Resources
-		if hex.Entity != nil && hex.Entity.HasFlower {
-			resourcesLeft++
-		}
+func (gs *GameState) applyPickupOrder(order *Order) {
+	bee := gs.getUnit(order)
+	if bee == nil {
+		return
 	}
 
-	if resourcesLeft == 0 {
-		gs.GameOver = true
+	hex := gs.Hexes[order.Coords]
+	if bee.HasFlower || hex.Flowers == 0 {
+		order.Status = CANNOT_PICKUP
+		return
 	}
 
-	// No influence change in a while
+	hex.Flowers--
+	bee.HasFlower = true
 
-	if gs.Turn-gs.LastResourceChange > RESOURCE_TIMEOUT {
-		gs.GameOver = true
+	order.Status = OK
+}
+
+func (gs *GameState) applySpawnOrder(order *Order) {
+	if gs.getUnit(order) == nil {
+		return
+	}
+	if gs.TargetIsBlocked(order) {
+		return
+	}
+	if !gs.canSupportBee(order.Player) {
+		order.Status = CAPACITY_REACHED
+		return
+	}
+	if !gs.tryToPay(order, gs.spawnCost(gs.countUnits(BEE)[order.Player])) {
+		return
 	}
 
-	// Determine winners
+	bee := gs.newEntity(BEE, order.Player)
+	gs.Hexes[order.Target()].Entity = bee
 
-	if gs.GameOver {
-		maxResources := slices.Max(gs.PlayerResources)
-		for player, resources := range gs.PlayerResources {
-			if resources == maxResources {
-				gs.Winners = append(gs.Winners, player)
-			}
-		}
-	}
+	order.Status = OK
 }
 
-func (gs *GameState) isVisibleBy(coords Coords, player int) bool {
-	for hcoords, hex := range gs.Hexes {
-		if hex.Entity != nil &&
-			hex.Entity.Player == player &&
-			hcoords.Distance(coords) <= FIELD_OF_VIEW {
-			return true
-		}
-	}
-	return false
+func (gs *GameState) PlayerView(player int) *GameState {
+	return gs.viewOf(player, gs.VisibleHexes(player))
 }
 
-func (gs *GameState) PlayerView(player int) *GameState {
+func (gs *GameState) viewOf(player int, visible map[Coords]bool) *GameState {
 	view := &GameState{
 		NumPlayers:         gs.NumPlayers,
 		Turn:               gs.Turn,
@@ -449,23 +813,32 @@ func (gs *GameState) PlayerView(player int) *GameState {
 		LastResourceChange: gs.LastResourceChange,
 		Winners:            gs.Winners,
 		GameOver:           gs.GameOver,
+		EndReason:          gs.EndReason,
+		HillPoints:         gs.HillPoints,
+		Eliminated:         gs.Eliminated,
+		Rules:              gs.Rules,
 	}
 
-	for coords, hex := range gs.Hexes {
-		if gs.isVisibleBy(coords, player) {
-			view.Hexes[coords] = hex
-		}
+	for coords := range visible {
+		view.Hexes[coords] = gs.Hexes[coords]
 	}
 
-	resources := slices.Clone(gs.PlayerResources)
-	for i := range resources {
+	view.PlayerResources = onlyPlayer(gs.PlayerResources, player)
+	view.PlayerBees = onlyPlayer(gs.PlayerBees, player)
+	view.SpawnCosts = onlyPlayer(gs.SpawnCosts, player)
+
+	return view
+}
+
+// onlyPlayer keeps the value of the given player, and zeroes the others
+func onlyPlayer(values []uint, player int) []uint {
+	result := slices.Clone(values)
+	for i := range result {
 		if i != player {
-			resources[i] = 0
+			result[i] = 0
 		}
 	}
-	view.PlayerResources = resources
-
-	return view
+	return result
 }
 
 func (gs *GameState) Clone() *GameState {
@@ -477,6 +850,10 @@ func (gs *GameState) Clone() *GameState {
 			copy := *hex.Entity
 			hex.Entity = &copy
 		}
+		if hex.Wall != nil {
+			copy := *hex.Wall
+			hex.Wall = &copy
+		}
 
 		hexes[k] = &hex
 	}
@@ -489,5 +866,11 @@ func (gs *GameState) Clone() *GameState {
 		LastResourceChange: gs.LastResourceChange,
 		Winners:            slices.Clone(gs.Winners),
 		GameOver:           gs.GameOver,
+		EndReason:          gs.EndReason,
+		HillPoints:         slices.Clone(gs.HillPoints),
+		Eliminated:         slices.Clone(gs.Eliminated),
+		PlayerBees:         slices.Clone(gs.PlayerBees),
+		SpawnCosts:         slices.Clone(gs.SpawnCosts),
+		Rules:              gs.Rules,
 	}
 }
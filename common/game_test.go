@@ -0,0 +1,360 @@
+package common
+
+import "testing"
+
+// testState starts a game on the balanced map
+func testState(t *testing.T, players int) *GameState {
+	mapData, err := LoadMap("../maps/balanced.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewGameState(mapData, players)
+}
+
+// firstUnit finds a unit of the player, and an empty hex next to it
+func firstUnit(t *testing.T, gs *GameState, player int, kind EntityType) (Coords, Direction) {
+	for coords, hex := range gs.Hexes {
+		if hex.Entity == nil || hex.Entity.Type != kind || hex.Entity.Player != player {
+			continue
+		}
+		for dir, offset := range DirectionToOffset {
+			target := gs.Hexes[Coords{Row: coords.Row + offset.Row, Col: coords.Col + offset.Col}]
+			if target != nil && target.Terrain.IsWalkable() && target.Entity == nil {
+				return coords, dir
+			}
+		}
+	}
+	t.Fatalf("no %s with room next to it", kind)
+	return Coords{}, ""
+}
+
+// firstBee finds a bee of the player, and an empty hex next to it
+func firstBee(t *testing.T, gs *GameState, player int) (Coords, Direction) {
+	return firstUnit(t, gs, player, BEE)
+}
+
+func TestWallDecay(t *testing.T) {
+	for _, decay := range []uint{1, 3} {
+		mapData, err := LoadMap("../maps/balanced.txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		mapData.Rules.WallDecay = decay
+
+		gs := NewGameState(mapData, 2)
+		gs.PlayerResources[0] = 10
+
+		coords, dir := firstBee(t, gs, 0)
+		wall := coords.Neighbour(dir)
+
+		orders := [][]*Order{{{Type: BUILD_WALL, Coords: coords, Direction: dir}}, nil}
+		results, _ := gs.ProcessOrders(orders)
+		if results[0].Status != OK {
+			t.Fatalf("could not build wall: %s", results[0].Status)
+		}
+
+		// Count the published states that show the wall
+		published := 0
+		for gs.EntityAt(wall) != nil && published <= int(decay)+1 {
+			published++
+			gs.ProcessOrders([][]*Order{nil, nil})
+		}
+
+		if published != int(decay) {
+			t.Errorf("wall with decay %d was visible for %d turns", decay, published)
+		}
+	}
+}
+
+func TestKilledBeeDropsFlower(t *testing.T) {
+	mapData, err := LoadMap("../maps/balanced.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gs := NewGameState(mapData, 2)
+	gs.Rules.StunChance = 0
+
+	// An enemy bee carrying a flower, on an empty hex next to a bee of player 0
+	coords, dir := firstBee(t, gs, 0)
+	target := coords.Neighbour(dir)
+	gs.Hexes[target].Terrain = EMPTY
+	gs.Hexes[target].Entity = &Entity{Type: BEE, Player: 1, HasFlower: true, Health: 1}
+
+	orders := [][]*Order{{{Type: ATTACK, Coords: coords, Direction: dir}}, nil}
+	results, _ := gs.ProcessOrders(orders)
+
+	if results[0].Status != KILLED {
+		t.Fatalf("attack status: %s", results[0].Status)
+	}
+	if gs.EntityAt(target) != nil {
+		t.Error("killed bee still on the map")
+	}
+	if gs.Hexes[target].Flowers != 1 {
+		t.Errorf("%d flowers on the ground, expected 1", gs.Hexes[target].Flowers)
+	}
+}
+
+func TestDropAndPickup(t *testing.T) {
+	gs := openState()
+
+	// A bee carrying a flower, and an empty handed one, side by side
+	carrier, empty := Coords{Row: 3, Col: 7}, Coords{Row: 3, Col: 9}
+	gs.Hexes[carrier].Entity = &Entity{Type: BEE, Player: 0, HasFlower: true}
+	gs.Hexes[empty].Entity = &Entity{Type: BEE, Player: 0}
+
+	tests := []struct {
+		coords  Coords
+		order   OrderType
+		status  OrderStatus
+		flowers uint
+		carries bool
+	}{
+		{empty, DROP, CANNOT_DROP, 0, false},
+		{empty, PICKUP, CANNOT_PICKUP, 0, false},
+		{carrier, PICKUP, CANNOT_PICKUP, 0, true},
+		{carrier, DROP, OK, 1, false},
+		{carrier, PICKUP, OK, 0, true},
+	}
+
+	for _, test := range tests {
+		results, _ := gs.ProcessOrders([][]*Order{{{Type: test.order, Coords: test.coords}}, nil})
+		if results[0].Status != test.status {
+			t.Errorf("%s at %v: status %s, expected %s", test.order, test.coords, results[0].Status, test.status)
+		}
+		hex := gs.Hexes[test.coords]
+		if hex.Flowers != test.flowers || hex.Entity.HasFlower != test.carries {
+			t.Errorf("%s at %v: %d flowers on the ground, bee carrying %t", test.order, test.coords, hex.Flowers, hex.Entity.HasFlower)
+		}
+	}
+}
+
+func TestGroundFlowersNotDepleted(t *testing.T) {
+	gs := openState()
+	field := Coords{Row: 0, Col: 14}
+	gs.Hexes[field].Resources = 0
+
+	// A flower left on the ground can still be brought home
+	ground := Coords{Row: 3, Col: 7}
+	gs.Hexes[ground].Flowers = 1
+	gs.ProcessOrders([][]*Order{nil, nil})
+	if gs.GameOver {
+		t.Fatalf("game over with a flower on the ground: %s", gs.EndReason)
+	}
+
+	gs.Hexes[ground].Flowers = 0
+	gs.ProcessOrders([][]*Order{nil, nil})
+	if !gs.GameOver || gs.EndReason != DEPLETED {
+		t.Errorf("game over %t (%s) without any flowers left", gs.GameOver, gs.EndReason)
+	}
+}
+
+func TestRegrowth(t *testing.T) {
+	mapData, errs := ParseMap("F1  H0  B0  .   H3  B3")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	mapData.Rules.RegrowthInterval = 3
+	mapData.Rules.RegrowthCap = 3
+
+	gs := NewGameState(mapData, 2)
+
+	// The bee of player 0 stands on the field, next to its hive
+	field := Coords{Row: 0, Col: 0}
+	gs.Hexes[field].Entity = gs.Hexes[Coords{Row: 0, Col: 4}].Entity
+	gs.Hexes[Coords{Row: 0, Col: 4}].Entity = nil
+
+	// Gather the only flower, then bring it home
+	for turn := 1; turn <= 2; turn++ {
+		results, _ := gs.ProcessOrders([][]*Order{{{Type: FORAGE, Coords: field}}, nil})
+		if results[0].Status != OK {
+			t.Fatalf("turn %d: forage status %s", turn, results[0].Status)
+		}
+	}
+
+	// No flower left anywhere, but the field will regrow
+	if gs.Hexes[field].Resources != 0 || gs.PlayerResources[0] != 1 {
+		t.Fatalf("%d flowers in the field, %d delivered", gs.Hexes[field].Resources, gs.PlayerResources[0])
+	}
+	if gs.GameOver {
+		t.Fatalf("game over without flowers but with regrowth: %s", gs.EndReason)
+	}
+
+	// One flower every 3 turns, up to the cap
+	for turn := 3; turn <= 12; turn++ {
+		gs.ProcessOrders([][]*Order{nil, nil})
+		expected := min(uint(turn/3), 3)
+		if gs.Hexes[field].Resources != expected {
+			t.Errorf("turn %d: %d flowers, expected %d", turn, gs.Hexes[field].Resources, expected)
+		}
+	}
+}
+
+// openState gives a two player game on an empty rectangle of hexes, with only
+// a hive for each player in the corners, and a field
+func openState() *GameState {
+	gs := &GameState{
+		NumPlayers:      2,
+		Hexes:           make(map[Coords]*Hex),
+		PlayerResources: make([]uint, 2),
+		Eliminated:      make([]bool, 2),
+		Rules:           DefaultRules(),
+	}
+	for row := range 7 {
+		for col := row % 2; col < 16; col += 2 {
+			gs.Hexes[Coords{Row: row, Col: col}] = &Hex{Terrain: EMPTY}
+		}
+	}
+
+	gs.Hexes[Coords{Row: 0, Col: 0}].Entity = &Entity{Type: HIVE, Player: 0}
+	gs.Hexes[Coords{Row: 6, Col: 14}].Entity = &Entity{Type: HIVE, Player: 1}
+	gs.Hexes[Coords{Row: 0, Col: 14}] = &Hex{Terrain: FIELD, Resources: INIT_FIELD_FLOWERS}
+	return gs
+}
+
+func TestSimultaneousMoves(t *testing.T) {
+	c := Coords{Row: 3, Col: 7}
+
+	type move struct {
+		from   Coords
+		dir    Direction
+		status OrderStatus
+		to     Coords
+	}
+
+	tests := []struct {
+		name  string
+		moves []move
+	}{
+		{"two bees entering one hex", []move{
+			{c.Neighbour(W), E, COLLISION, c.Neighbour(W)},
+			{c.Neighbour(E), W, COLLISION, c.Neighbour(E)},
+		}},
+		{"swap", []move{
+			{c, E, OK, c.Neighbour(E)},
+			{c.Neighbour(E), W, OK, c},
+		}},
+		{"rotation", []move{
+			{c, E, OK, c.Neighbour(E)},
+			{c.Neighbour(E), SW, OK, c.Neighbour(SE)},
+			{c.Neighbour(SE), NW, OK, c},
+		}},
+		{"chain behind a bounced bee", []move{
+			{c.Neighbour(W), E, COLLISION, c.Neighbour(W)},
+			{c.Neighbour(E), W, COLLISION, c.Neighbour(E)},
+			{c.Neighbour(W).Neighbour(W), E, BLOCKED, c.Neighbour(W).Neighbour(W)},
+			{c.Neighbour(W).Neighbour(W).Neighbour(W), E, BLOCKED, c.Neighbour(W).Neighbour(W).Neighbour(W)},
+		}},
+		{"hex vacated in the same round", []move{
+			{c.Neighbour(W), E, OK, c},
+			{c, E, OK, c.Neighbour(E)},
+		}},
+	}
+
+	for _, test := range tests {
+		gs := openState()
+
+		var orders []*Order
+		var bees []*Entity
+		for i, m := range test.moves {
+			bee := &Entity{Type: BEE, Player: i % 2}
+			gs.Hexes[m.from].Entity = bee
+			bees = append(bees, bee)
+			orders = append(orders, &Order{Type: MOVE, Player: i % 2, Coords: m.from, Direction: m.dir})
+		}
+
+		gs.resolveMoves(orders, make(map[*Entity]bool))
+
+		for i, m := range test.moves {
+			if orders[i].Status != m.status {
+				t.Errorf("%s: move %d: status %s, expected %s", test.name, i, orders[i].Status, m.status)
+			}
+			if gs.EntityAt(m.to) != bees[i] {
+				t.Errorf("%s: move %d: bee not at %v", test.name, i, m.to)
+			}
+		}
+	}
+}
+
+func TestPassThroughWalls(t *testing.T) {
+	for _, resolution := range []MoveResolution{SEQUENTIAL, SIMULTANEOUS} {
+		gs := openState()
+		gs.Rules.PassThroughWalls = true
+		gs.Rules.MoveResolution = resolution
+
+		// A bee of player 0, its wall to the east, and a rock beyond
+		c := Coords{Row: 3, Col: 7}
+		wallCoords := c.Neighbour(E)
+		bee := &Entity{Type: BEE, Player: 0}
+		wall := &Entity{Type: WALL, Player: 0}
+		enemyWall := &Entity{Type: WALL, Player: 1}
+		gs.Hexes[c].Entity = bee
+		gs.Hexes[wallCoords].Entity = wall
+		gs.Hexes[wallCoords.Neighbour(E)].Terrain = ROCK
+		gs.Hexes[c.Neighbour(W)].Entity = enemyWall
+
+		move := func(from Coords, dir Direction) OrderStatus {
+			results, _ := gs.ProcessOrders([][]*Order{{{Type: MOVE, Coords: from, Direction: dir}}, nil})
+			return results[0].Status
+		}
+
+		// The bee stands in its wall, one step away, and the rock still blocks it
+		if status := move(c, E); status != OK || gs.EntityAt(wallCoords) != bee || gs.Hexes[wallCoords].Wall != wall {
+			t.Fatalf("%s: moving into own wall: %s", resolution, status)
+		}
+		if status := move(wallCoords, E); status != BLOCKED {
+			t.Errorf("%s: moving from the wall into a rock: %s", resolution, status)
+		}
+
+		// Leaving the wall leaves it in place
+		if status := move(wallCoords, W); status != OK || gs.EntityAt(c) != bee || gs.EntityAt(wallCoords) != wall || gs.Hexes[wallCoords].Wall != nil {
+			t.Errorf("%s: leaving own wall: %s", resolution, status)
+		}
+
+		// Walls of other players still block
+		if status := move(c, W); status != BLOCKED || gs.EntityAt(c.Neighbour(W)) != enemyWall {
+			t.Errorf("%s: moving into an enemy wall: %s", resolution, status)
+		}
+	}
+}
+
+func TestKilledBeeInWall(t *testing.T) {
+	gs := openState()
+	c := Coords{Row: 3, Col: 7}
+	wall := &Entity{Type: WALL, Player: 0}
+	gs.Hexes[c].Entity = &Entity{Type: BEE, Player: 0}
+	gs.Hexes[c].Wall = wall
+
+	gs.killBee(c)
+
+	if gs.EntityAt(c) != wall || gs.Hexes[c].Wall != nil {
+		t.Error("wall not left in place by a killed bee")
+	}
+}
+
+func TestFollowingIntoLeftWall(t *testing.T) {
+	gs := openState()
+	gs.Rules.PassThroughWalls = true
+
+	// A bee of player 0 standing in its wall, and a bee of player 1 following
+	// into the hex it leaves
+	c := Coords{Row: 3, Col: 7}
+	wall := &Entity{Type: WALL, Player: 0}
+	enemy := &Entity{Type: BEE, Player: 1}
+	gs.Hexes[c].Entity = &Entity{Type: BEE, Player: 0}
+	gs.Hexes[c].Wall = wall
+	gs.Hexes[c.Neighbour(E)].Entity = enemy
+
+	orders := []*Order{
+		{Type: MOVE, Player: 0, Coords: c, Direction: W},
+		{Type: MOVE, Player: 1, Coords: c.Neighbour(E), Direction: W},
+	}
+	gs.resolveMoves(orders, make(map[*Entity]bool))
+
+	if orders[0].Status != OK || orders[1].Status != BLOCKED {
+		t.Errorf("statuses %s and %s", orders[0].Status, orders[1].Status)
+	}
+	if gs.EntityAt(c) != wall || gs.EntityAt(c.Neighbour(E)) != enemy {
+		t.Error("enemy bee moved into the wall left behind")
+	}
+}
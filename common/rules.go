@@ -0,0 +1,265 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+type MoveResolution string
+
+const (
+	SEQUENTIAL   MoveResolution = "SEQUENTIAL"
+	SIMULTANEOUS MoveResolution = "SIMULTANEOUS"
+)
+
+type VictoryCondition string
+
+const (
+	MOST_FLOWERS     VictoryCondition = "MOST_FLOWERS"
+	TURN_LIMIT       VictoryCondition = "TURN_LIMIT"
+	FIRST_TO_FLOWERS VictoryCondition = "FIRST_TO_FLOWERS"
+	KING_OF_THE_HILL VictoryCondition = "KING_OF_THE_HILL"
+	LAST_HIVE        VictoryCondition = "LAST_HIVE"
+)
+
+// Rules holds the values that can vary from one game to another. Maps can
+// override them, and they are sent to the players along with the game state.
+type Rules struct {
+	InitFieldFlowers uint    `json:"initFieldFlowers"`
+	BeeCost          uint    `json:"beeCost"`
+	HiveCost         uint    `json:"hiveCost"`
+	WallCost         uint    `json:"wallCost"`
+	WallAttackChance float64 `json:"wallAttackChance"`
+	HiveHealth       uint    `json:"hiveHealth"`
+	BeeHealth        uint    `json:"beeHealth"`
+	StunChance       float64 `json:"stunChance"`
+	StunTurns        uint    `json:"stunTurns"`
+	ResourceTimeout  uint    `json:"resourceTimeout"`
+
+	// Whether the moves of a round are applied one after the other, in the
+	// shuffled order of the round, or all at once
+	MoveResolution MoveResolution `json:"moveResolution"`
+
+	// How far each type of entity sees, and whether rocks block the view
+	HiveSight     int  `json:"hiveSight"`
+	BeeSight      int  `json:"beeSight"`
+	WallSight     int  `json:"wallSight"`
+	RockOcclusion bool `json:"rockOcclusion"`
+
+	// Walls: whether bees can dismantle the walls of their player, getting
+	// WallRefund flowers back, and jump over them, and how many turns walls
+	// last (0 for walls that never decay)
+	DismantleWalls   bool `json:"dismantleWalls"`
+	WallRefund       uint `json:"wallRefund"`
+	PassThroughWalls bool `json:"passThroughWalls"`
+	WallDecay        uint `json:"wallDecay"`
+
+	// Economy: how many bees each hive supports (0 for no limit), how many
+	// flowers each bee costs every UpkeepInterval turns (0 for no upkeep), and
+	// how much the cost of a bee grows for each bee the player already has
+	HiveCapacity   uint `json:"hiveCapacity"`
+	BeeUpkeep      uint `json:"beeUpkeep"`
+	UpkeepInterval uint `json:"upkeepInterval"`
+	SpawnCostScale uint `json:"spawnCostScale"`
+
+	// Fields regrow one flower every RegrowthInterval turns (0 disables
+	// regrowth), up to RegrowthCap flowers (0 for their initial amount)
+	RegrowthInterval uint `json:"regrowthInterval"`
+	RegrowthCap      uint `json:"regrowthCap"`
+
+	// How the game is won. TurnLimit ends any game after that many turns (0
+	// for no limit), TargetFlowers and HillScore are the goals of the first
+	// to flowers and king of the hill games
+	Victory       VictoryCondition `json:"victory"`
+	TurnLimit     uint             `json:"turnLimit"`
+	TargetFlowers uint             `json:"targetFlowers"`
+	HillScore     uint             `json:"hillScore"`
+}
+
+func DefaultRules() Rules {
+	return Rules{
+		InitFieldFlowers: INIT_FIELD_FLOWERS,
+		BeeCost:          BEE_COST,
+		HiveCost:         HIVE_COST,
+		WallCost:         WALL_COST,
+		WallAttackChance: WALL_ATTACK_CHANCE,
+		HiveHealth:       HIVE_HEALTH,
+		BeeHealth:        BEE_HEALTH,
+		StunChance:       STUN_CHANCE,
+		StunTurns:        STUN_TURNS,
+		HiveSight:        HIVE_SIGHT,
+		BeeSight:         BEE_SIGHT,
+		WallSight:        WALL_SIGHT,
+		ResourceTimeout:  RESOURCE_TIMEOUT,
+		MoveResolution:   SEQUENTIAL,
+		UpkeepInterval:   UPKEEP_INTERVAL,
+		Victory:          MOST_FLOWERS,
+	}
+}
+
+func (rules Rules) Regrowth() bool {
+	return rules.RegrowthInterval > 0
+}
+
+func (rules Rules) Validate() error {
+	if rules.WallAttackChance < 0 || rules.WallAttackChance > 1 {
+		return fmt.Errorf("wallAttackChance should be between 0 and 1")
+	}
+	if rules.StunChance < 0 || rules.StunChance > 1 {
+		return fmt.Errorf("stunChance should be between 0 and 1")
+	}
+	if rules.WallRefund > rules.WallCost {
+		return fmt.Errorf("wallRefund should not exceed wallCost")
+	}
+	if rules.BeeUpkeep > 0 && rules.UpkeepInterval == 0 {
+		return fmt.Errorf("upkeepInterval is needed for games with upkeep")
+	}
+	if rules.HiveSight < 0 || rules.BeeSight < 0 || rules.WallSight < 0 {
+		return fmt.Errorf("hiveSight, beeSight and wallSight should not be negative")
+	}
+
+	if rules.MoveResolution != SEQUENTIAL && rules.MoveResolution != SIMULTANEOUS {
+		return fmt.Errorf("invalid move resolution: %s", rules.MoveResolution)
+	}
+
+	switch rules.Victory {
+	case MOST_FLOWERS, LAST_HIVE:
+	case TURN_LIMIT:
+		if rules.TurnLimit == 0 {
+			return fmt.Errorf("turnLimit is needed for %s games", rules.Victory)
+		}
+	case FIRST_TO_FLOWERS:
+		if rules.TargetFlowers == 0 {
+			return fmt.Errorf("targetFlowers is needed for %s games", rules.Victory)
+		}
+	case KING_OF_THE_HILL:
+		if rules.HillScore == 0 {
+			return fmt.Errorf("hillScore is needed for %s games", rules.Victory)
+		}
+	default:
+		return fmt.Errorf("invalid victory condition: %s", rules.Victory)
+	}
+
+	return nil
+}
+
+// CheckRules tells whether games on the map can be played with the given rules
+func (m MapData) CheckRules(rules Rules) error {
+	err := rules.Validate()
+	if err != nil {
+		return err
+	}
+
+	if rules.Victory == KING_OF_THE_HILL && len(m.Hills) == 0 {
+		return fmt.Errorf("%s games need a map with hills", rules.Victory)
+	}
+
+	return nil
+}
+
+// FieldOfView is the former name of a single sight distance for all the types
+// of entities. It is still accepted in maps, overrides and locked rules, as a
+// shorthand for the three sight rules.
+const FieldOfView = "fieldOfView"
+
+var sightRules = []string{"hiveSight", "beeSight", "wallSight"}
+
+// UnmarshalJSON reads rules, applying fieldOfView before the sight rules given
+// explicitly
+func (rules *Rules) UnmarshalJSON(data []byte) error {
+	var alias map[string]json.RawMessage
+	err := json.Unmarshal(data, &alias)
+	if err != nil {
+		return err
+	}
+
+	if value, found := alias[FieldOfView]; found {
+		var sight int
+		err = json.Unmarshal(value, &sight)
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: %s", FieldOfView, value)
+		}
+		rules.HiveSight, rules.BeeSight, rules.WallSight = sight, sight, sight
+	}
+
+	type plain Rules
+	return json.Unmarshal(data, (*plain)(rules))
+}
+
+// expandFieldOfView replaces fieldOfView in a list of rule names by the three
+// sight rules
+func expandFieldOfView(names []string) []string {
+	if !slices.Contains(names, FieldOfView) {
+		return names
+	}
+	names = slices.DeleteFunc(slices.Clone(names), func(name string) bool { return name == FieldOfView })
+	return append(names, sightRules...)
+}
+
+func (rules Rules) fields() map[string]any {
+	fields := make(map[string]any)
+	encoded, _ := json.Marshal(rules)
+	json.Unmarshal(encoded, &fields)
+	return fields
+}
+
+// IsRule tells whether a name is the JSON name of a rule
+func IsRule(name string) bool {
+	_, found := DefaultRules().fields()[name]
+	return found || name == FieldOfView
+}
+
+// Override changes rules from text values, such as query string parameters,
+// given by the JSON names of the rules. Unknown names and locked rules are
+// refused.
+func (rules Rules) Override(values map[string]string, locked []string) (Rules, error) {
+	fields := rules.fields()
+	locked = expandFieldOfView(locked)
+
+	if sight, found := values[FieldOfView]; found {
+		values = maps.Clone(values)
+		delete(values, FieldOfView)
+		for _, name := range sightRules {
+			if _, found := values[name]; !found {
+				values[name] = sight
+			}
+		}
+	}
+
+	for name, value := range values {
+		if slices.Contains(locked, name) {
+			return rules, fmt.Errorf("%s is locked by the map", name)
+		}
+
+		switch fields[name].(type) {
+		case string:
+			fields[name] = value
+		case float64:
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return rules, fmt.Errorf("invalid value for %s: %s", name, value)
+			}
+			fields[name] = number
+		case bool:
+			flag, err := strconv.ParseBool(value)
+			if err != nil {
+				return rules, fmt.Errorf("invalid value for %s: %s", name, value)
+			}
+			fields[name] = flag
+		default:
+			return rules, fmt.Errorf("unknown rule: %s", name)
+		}
+	}
+
+	var result Rules
+	encoded, _ := json.Marshal(fields)
+	err := json.Unmarshal(encoded, &result)
+	if err != nil {
+		return rules, fmt.Errorf("invalid rules: %w", err)
+	}
+
+	return result, result.Validate()
+}
@@ -0,0 +1,49 @@
+package common
+
+import "testing"
+
+func TestOverride(t *testing.T) {
+	rules, err := DefaultRules().Override(map[string]string{"beeCost": "3", "rockOcclusion": "true"}, nil)
+	if err != nil || rules.BeeCost != 3 || !rules.RockOcclusion {
+		t.Errorf("rules not overridden: %v", err)
+	}
+
+	for _, values := range []map[string]string{
+		{"beeCosts": "3"},
+		{"beeCost": "-1"},
+		{"hiveCost": "3", "beeCost": "3"},
+	} {
+		_, err := DefaultRules().Override(values, []string{"beeCost"})
+		if err == nil {
+			t.Errorf("%v accepted", values)
+		}
+	}
+}
+
+func TestLockedRules(t *testing.T) {
+	mapData, errs := ParseMapJSON([]byte(`{"lockedRules": ["beeCost", "beeCosts"], "map": [".   ."]}`))
+
+	if len(mapData.LockedRules) != 2 || len(errs) != 1 || errs[0].Error() != "unknown locked rule: beeCosts" {
+		t.Errorf("locked rules %v, errors %v", mapData.LockedRules, errs)
+	}
+}
+
+func TestFieldOfView(t *testing.T) {
+	mapData, errs := ParseMapJSON([]byte(`{"rules": {"fieldOfView": 2, "beeSight": 5}, "lockedRules": ["fieldOfView"], "map": [".   ."]}`))
+	rules := mapData.Rules
+	if len(errs) > 0 || rules.HiveSight != 2 || rules.BeeSight != 5 || rules.WallSight != 2 {
+		t.Errorf("map sights %d %d %d, errors %v", rules.HiveSight, rules.BeeSight, rules.WallSight, errs)
+	}
+
+	rules, err := DefaultRules().Override(map[string]string{"fieldOfView": "3"}, nil)
+	if err != nil || rules.HiveSight != 3 || rules.BeeSight != 3 || rules.WallSight != 3 {
+		t.Errorf("overridden sights %d %d %d (%v)", rules.HiveSight, rules.BeeSight, rules.WallSight, err)
+	}
+
+	for _, values := range []map[string]string{{"fieldOfView": "3"}, {"beeSight": "3"}} {
+		_, err := DefaultRules().Override(values, []string{"fieldOfView"})
+		if err == nil {
+			t.Errorf("%v accepted with locked field of view", values)
+		}
+	}
+}
@@ -46,8 +46,17 @@ type PersistedGame struct {
 }
 
 type Turn struct {
-	Orders []*Order   `json:"orders,omitempty"`
-	State  *GameState `json:"state"`
+	Orders []*Order      `json:"orders,omitempty"`
+	State  *GameState    `json:"state"`
+	Clock  []PlayerClock `json:"clock,omitempty"`
+}
+
+// PlayerClock records how a player used their time during a turn
+type PlayerClock struct {
+	Latency   int64 `json:"latency"`
+	TimedOut  bool  `json:"timedOut,omitzero"`
+	TimeBank  int64 `json:"timeBank,omitzero"`
+	Forfeited bool  `json:"forfeited,omitzero"`
 }
 
 type SessionStatus struct {
@@ -57,9 +66,83 @@ type SessionStatus struct {
 	NumPlayers  int       `json:"numPlayers"`
 	Players     []string  `json:"players"`
 	GameOver    bool      `json:"gameOver"`
+	Paused      bool      `json:"paused"`
+
+	// Turn timing, in milliseconds, and turn limit (0 for none)
+	TurnTimeout     int64 `json:"turnTimeout"`
+	MinTurnDuration int64 `json:"minTurnDuration"`
+	MaxTurns        uint  `json:"maxTurns"`
+
+	// Time bank and increment, in milliseconds, and number of timeouts
+	// before forfeit (0 when not used)
+	TimeBank      int64 `json:"timeBank"`
+	TimeIncrement int64 `json:"timeIncrement"`
+	MaxTimeouts   int   `json:"maxTimeouts"`
 }
 
 type StatusResponse struct {
 	GitRevision string          `json:"gitRevision"`
 	Games       []SessionStatus `json:"games"`
+	Queue       []QueueStatus   `json:"queue"`
+}
+
+type QueueStatus struct {
+	Name    string    `json:"name"`
+	Players int       `json:"players"`
+	Maps    []string  `json:"maps"`
+	Since   time.Time `json:"since"`
+}
+
+type MapInfo struct {
+	Id          string   `json:"id"`
+	Name        string   `json:"name,omitempty"`
+	Author      string   `json:"author,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Players     []int    `json:"players"`
+	Rules       Rules    `json:"rules"`
+	LockedRules []string `json:"lockedRules,omitempty"`
+}
+
+type TeamInfo struct {
+	Name   string  `json:"name"`
+	Rating float64 `json:"rating"`
+	Played int     `json:"played"`
+	Won    int     `json:"won"`
+}
+
+// TeamGame records a game played by a registered team
+type TeamGame struct {
+	Id        string    `json:"id"`
+	Map       string    `json:"map"`
+	Date      time.Time `json:"date"`
+	History   string    `json:"history"`
+	Players   []string  `json:"players"`
+	Player    int       `json:"player"`
+	Won       bool      `json:"won"`
+	EndReason EndReason `json:"endReason"`
+	Rating    float64   `json:"rating"`
+}
+
+type TeamHistory struct {
+	TeamInfo
+	Games []TeamGame `json:"games"`
+}
+
+// GameSummary describes a past game, without its history
+type GameSummary struct {
+	Id          string    `json:"id"`
+	Map         string    `json:"map"`
+	CreatedDate time.Time `json:"createdDate"`
+	Players     []string  `json:"players"`
+	Winners     []string  `json:"winners"`
+	EndReason   EndReason `json:"endReason"`
+	Turns       uint      `json:"turns"`
+	File        string    `json:"file"`
+}
+
+type HistorySearchResponse struct {
+	Total  int           `json:"total"`
+	Offset int           `json:"offset"`
+	Limit  int           `json:"limit"`
+	Games  []GameSummary `json:"games"`
 }
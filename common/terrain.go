@@ -1,7 +1,11 @@
 package common
 
 import (
+	"encoding/json"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -80,6 +84,42 @@ type Spawn struct {
 type MapData struct {
 	Map    map[Coords]Terrain
 	Spawns []Spawn
+
+	// Initial flowers of the fields that do not use the default amount
+	Flowers map[Coords]uint
+
+	// Hexes to control in king of the hill games
+	Hills []Coords
+
+	// Optional metadata, only available in the JSON map format
+
+	Name        string
+	Author      string
+	Description string
+	Players     []int
+	Slots       map[int][]int
+	Rules       Rules
+	LockedRules []string
+}
+
+// MapFile is the JSON map format: the same text grid as in the plain map files,
+// given as an array of lines, along with metadata. Slots give, for a number of
+// players, the spawn slot of each player. Flowers give the initial amount of
+// specific fields, and the legend gives the amount for field keys used in the
+// grid. Rules only need to list the values that differ from the defaults, and
+// locked rules cannot be changed for a game on the map.
+type MapFile struct {
+	Name        string          `json:"name"`
+	Author      string          `json:"author"`
+	Description string          `json:"description"`
+	Players     []int           `json:"players,omitempty"`
+	Slots       map[int][]int   `json:"slots,omitempty"`
+	Flowers     map[Coords]uint `json:"flowers,omitempty"`
+	Legend      map[string]uint `json:"legend,omitempty"`
+	Hills       []Coords        `json:"hills,omitempty"`
+	Rules       Rules           `json:"rules"`
+	LockedRules []string        `json:"lockedRules,omitempty"`
+	Map         []string        `json:"map"`
 }
 
 var charToTerrain = map[rune]Terrain{
@@ -94,37 +134,173 @@ var charToSpawn = map[rune]EntityType{
 }
 
 func LoadMap(path string) (MapData, error) {
-	content, err := os.ReadFile(path)
+	mapData, errs, err := ReadMap(path)
 	if err != nil {
 		return MapData{}, err
 	}
 
-	lines := strings.Split(string(content), "\n")
+	for _, err := range errs {
+		if !err.Warning {
+			return MapData{}, err
+		}
+	}
+
+	return mapData, nil
+}
+
+// ReadMap parses and validates a map file, in the text format or in the JSON
+// format if its name ends with .json, and returns all the problems found.
+func ReadMap(path string) (MapData, []MapError, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return MapData{}, nil, err
+	}
+
+	var mapData MapData
+	var errs []MapError
+
+	if filepath.Ext(path) == ".json" {
+		mapData, errs = ParseMapJSON(content)
+	} else {
+		mapData, errs = ParseMap(string(content))
+	}
+
+	errs = append(errs, ValidateMap(mapData)...)
+	return mapData, errs, nil
+}
+
+func ParseMapJSON(content []byte) (MapData, []MapError) {
+	file := MapFile{Rules: DefaultRules()}
+	err := json.Unmarshal(content, &file)
+	if err != nil {
+		return MapData{}, []MapError{mapError(0, "invalid JSON: %s", err)}
+	}
+
+	legend := make(map[byte]uint)
+	var errs []MapError
+	for _, key := range slices.Sorted(maps.Keys(file.Legend)) {
+		flowers := file.Legend[key]
+		if len(key) != 1 || key == " " {
+			errs = append(errs, mapError(0, "invalid legend key '%s', it should be a single character", key))
+			continue
+		}
+		legend[key[0]] = flowers
+	}
+
+	mapData, gridErrs := parseGrid(strings.Join(file.Map, "\n"), legend)
+	errs = append(errs, gridErrs...)
+
+	mapData.Name = file.Name
+	mapData.Author = file.Author
+	mapData.Description = file.Description
+	mapData.Players = file.Players
+	mapData.Slots = file.Slots
+	mapData.Rules = file.Rules
+	mapData.LockedRules = file.LockedRules
+
+	for _, name := range file.LockedRules {
+		if !IsRule(name) {
+			errs = append(errs, mapError(0, "unknown locked rule: %s", name))
+		}
+	}
+
+	for coords, flowers := range file.Flowers {
+		mapData.Flowers[coords] = flowers
+	}
+
+	for _, coords := range file.Hills {
+		if !slices.Contains(mapData.Hills, coords) {
+			mapData.Hills = append(mapData.Hills, coords)
+		}
+	}
+
+	return mapData, errs
+}
+
+func ParseMap(content string) (MapData, []MapError) {
+	return parseGrid(content, nil)
+}
+
+// parseGrid reads the text grid of a map. A field can be followed by a key
+// giving its initial amount of flowers: either a digit, for that many flowers,
+// or a character defined in the legend. Any terrain followed by a star is a
+// hill.
+func parseGrid(content string, legend map[byte]uint) (MapData, []MapError) {
+	lines := strings.Split(content, "\n")
 	gameMap := make(map[Coords]Terrain)
+	flowers := make(map[Coords]uint)
+	hills := []Coords{}
 	spawns := []Spawn{}
+	var errs []MapError
 
 	for row, line := range lines {
-		for col, char := range line {
+		line = strings.TrimRight(line, "\r")
+
+		for col := 0; col < len(line); col++ {
+			char := rune(line[col])
+			if char == ' ' {
+				continue
+			}
+
+			_, isTerrain := charToTerrain[char]
+			kind, isSpawn := charToSpawn[char]
+
+			if !isTerrain && !isSpawn {
+				errs = append(errs, mapError(row+1, "unknown character '%c' at column %d", char, col+1))
+				continue
+			}
+
 			coords := Coords{row, col / 2}
+			if col%2 != 0 || (coords.Row+coords.Col)%2 != 0 {
+				errs = append(errs, mapError(row+1, "misaligned hex '%c' at column %d", char, col+1))
+			}
 
-			if terrain, ok := charToTerrain[char]; ok {
-				gameMap[coords] = terrain
-			} else if kind, ok := charToSpawn[char]; ok {
-				playerStr := string(line[col+1])
-				player, _ := strconv.Atoi(playerStr)
-
-				spawns = append(spawns, Spawn{
-					Kind:   kind,
-					Player: player,
-					Coords: coords,
-				})
-				gameMap[coords] = EMPTY
+			if isTerrain {
+				gameMap[coords] = charToTerrain[char]
+
+				if col+1 < len(line) && line[col+1] == '*' {
+					col++
+					hills = append(hills, coords)
+				} else if char == 'F' && col+1 < len(line) && line[col+1] != ' ' {
+					col++
+					key := line[col]
+					if amount, found := legend[key]; found {
+						flowers[coords] = amount
+					} else if key >= '0' && key <= '9' {
+						flowers[coords] = uint(key - '0')
+					} else {
+						errs = append(errs, mapError(row+1, "unknown field key '%c' at column %d", key, col+1))
+					}
+				}
+				continue
+			}
+
+			if col+1 >= len(line) {
+				errs = append(errs, mapError(row+1, "missing player number after '%c' at column %d", char, col+1))
+				continue
 			}
+
+			col++
+			player, err := strconv.Atoi(string(line[col]))
+			if err != nil || player >= MAX_PLAYERS {
+				errs = append(errs, mapError(row+1, "invalid player number '%c' at column %d", line[col], col+1))
+				continue
+			}
+
+			spawns = append(spawns, Spawn{
+				Kind:   kind,
+				Player: player,
+				Coords: coords,
+			})
+			gameMap[coords] = EMPTY
 		}
 	}
 
 	return MapData{
-		Map:    gameMap,
-		Spawns: spawns,
-	}, nil
+		Map:     gameMap,
+		Spawns:  spawns,
+		Flowers: flowers,
+		Hills:   hills,
+		Rules:   DefaultRules(),
+	}, errs
 }
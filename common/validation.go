@@ -0,0 +1,225 @@
+package common
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// MapError describes a problem found in a map file. Line is 1-based, or 0 when
+// the problem concerns the map as a whole. Warnings flag maps that are playable
+// but probably not what the designer intended.
+type MapError struct {
+	Line    int
+	Message string
+	Warning bool
+}
+
+func (e MapError) Error() string {
+	if e.Line == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+func mapError(line int, format string, args ...any) MapError {
+	return MapError{Line: line, Message: fmt.Sprintf(format, args...)}
+}
+
+func mapWarning(line int, format string, args ...any) MapError {
+	return MapError{Line: line, Message: fmt.Sprintf(format, args...), Warning: true}
+}
+
+// Distances computes the number of moves needed to reach every walkable hex
+// from the closest of the given sources, ignoring entities. Unreachable hexes
+// are absent from the result.
+func (m MapData) Distances(sources []Coords) map[Coords]int {
+	dist := make(map[Coords]int)
+	queue := []Coords{}
+
+	for _, source := range sources {
+		if _, found := dist[source]; !found && m.Map[source].IsWalkable() {
+			dist[source] = 0
+			queue = append(queue, source)
+		}
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, n := range current.Neighbours() {
+			if _, found := dist[n]; found || !m.Map[n].IsWalkable() {
+				continue
+			}
+			dist[n] = dist[current] + 1
+			queue = append(queue, n)
+		}
+	}
+
+	return dist
+}
+
+func sortCoords(coords []Coords) {
+	slices.SortFunc(coords, func(a, b Coords) int {
+		if a.Row != b.Row {
+			return a.Row - b.Row
+		}
+		return a.Col - b.Col
+	})
+}
+
+func ValidateMap(mapData MapData) []MapError {
+	var errs []MapError
+
+	if len(mapData.Map) == 0 {
+		return []MapError{mapError(0, "map is empty")}
+	}
+
+	// Metadata
+
+	for _, n := range mapData.Players {
+		if !IsValidNumPlayers(n) {
+			errs = append(errs, mapError(0, "invalid number of players: %d", n))
+		}
+	}
+
+	var supported []int
+	for n := 1; n <= MAX_PLAYERS; n++ {
+		if mapData.SupportsNumPlayers(n) {
+			supported = append(supported, n)
+		}
+	}
+	if len(supported) == 0 {
+		errs = append(errs, mapError(0, "map does not support any number of players"))
+	}
+
+	for _, n := range slices.Sorted(maps.Keys(mapData.Slots)) {
+		slots := mapData.Slots[n]
+		if !mapData.SupportsNumPlayers(n) {
+			errs = append(errs, mapError(0, "slots given for unsupported number of players: %d", n))
+		}
+		if len(slots) != n {
+			errs = append(errs, mapError(0, "%d slots given for %d players", len(slots), n))
+		}
+		for i, slot := range slots {
+			if slot < 0 || slot >= MAX_PLAYERS || slices.Index(slots, slot) != i {
+				errs = append(errs, mapError(0, "invalid or duplicate slot %d for %d players", slot, n))
+			}
+		}
+	}
+
+	flowers := slices.Collect(maps.Keys(mapData.Flowers))
+	sortCoords(flowers)
+	for _, coords := range flowers {
+		if mapData.Map[coords] != FIELD {
+			errs = append(errs, mapError(coords.Row+1, "flowers given for %s, which is not a field", coords))
+		}
+	}
+
+	for _, coords := range mapData.Hills {
+		if !mapData.Map[coords].IsWalkable() {
+			errs = append(errs, mapError(coords.Row+1, "hill at %s is not on a walkable hex", coords))
+		}
+	}
+
+	if err := mapData.CheckRules(mapData.Rules); err != nil {
+		errs = append(errs, mapError(0, "invalid rules: %s", err))
+	}
+
+	// Spawns
+
+	hives := make([]int, MAX_PLAYERS)
+	bees := make([]int, MAX_PLAYERS)
+	occupied := make(map[Coords]bool)
+
+	for _, spawn := range mapData.Spawns {
+		line := spawn.Coords.Row + 1
+
+		if spawn.Player < 0 || spawn.Player >= MAX_PLAYERS {
+			errs = append(errs, mapError(line, "invalid player %d for spawn at %s", spawn.Player, spawn.Coords))
+			continue
+		}
+
+		if !mapData.Map[spawn.Coords].IsWalkable() {
+			errs = append(errs, mapError(line, "spawn at %s is not on a walkable hex", spawn.Coords))
+		}
+
+		if occupied[spawn.Coords] {
+			errs = append(errs, mapError(line, "several spawns at %s", spawn.Coords))
+		}
+		occupied[spawn.Coords] = true
+
+		switch spawn.Kind {
+		case HIVE:
+			hives[spawn.Player]++
+		case BEE:
+			bees[spawn.Player]++
+		default:
+			errs = append(errs, mapError(line, "invalid spawn kind %s at %s", spawn.Kind, spawn.Coords))
+		}
+	}
+
+	// Every slot used by a supported number of players needs a hive and a bee
+
+	used := make([]bool, MAX_PLAYERS)
+	for _, n := range supported {
+		for slot, player := range mapData.PlayerMapping(n) {
+			used[slot] = used[slot] || player != -1
+		}
+	}
+
+	for player := range MAX_PLAYERS {
+		if !used[player] {
+			continue
+		}
+		if hives[player] == 0 {
+			errs = append(errs, mapError(0, "player %d has no hive", player))
+		}
+		if bees[player] == 0 {
+			errs = append(errs, mapError(0, "player %d has no bee", player))
+		}
+	}
+
+	// Connectivity: every walkable hex should be reachable from any other. Some
+	// maps are split on purpose, so this is only a warning
+
+	var walkable, fields []Coords
+	for coords, terrain := range mapData.Map {
+		if terrain.IsWalkable() {
+			walkable = append(walkable, coords)
+		}
+		if terrain == FIELD {
+			fields = append(fields, coords)
+		}
+	}
+	sortCoords(walkable)
+
+	if len(walkable) > 0 {
+		reached := mapData.Distances(walkable[:1])
+		for _, coords := range walkable {
+			if _, found := reached[coords]; found {
+				continue
+			}
+			errs = append(errs, mapWarning(coords.Row+1, "hex at %s is not connected to the rest of the map", coords))
+			for c := range mapData.Distances([]Coords{coords}) {
+				reached[c] = 0
+			}
+		}
+	}
+
+	// Every spawn should be able to reach a field
+
+	if len(fields) == 0 {
+		errs = append(errs, mapError(0, "map has no field"))
+	} else {
+		fromFields := mapData.Distances(fields)
+		for _, spawn := range mapData.Spawns {
+			if _, found := fromFields[spawn.Coords]; !found && mapData.Map[spawn.Coords].IsWalkable() {
+				errs = append(errs, mapError(spawn.Coords.Row+1, "spawn at %s cannot reach any field", spawn.Coords))
+			}
+		}
+	}
+
+	return errs
+}
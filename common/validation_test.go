@@ -0,0 +1,83 @@
+package common
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseMapErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		err     MapError
+	}{
+		{"F   H", mapError(1, "missing player number after 'H' at column 5")},
+		{"F   B0  B", mapError(1, "missing player number after 'B' at column 9")},
+		{"F   H9", mapError(1, "invalid player number '9' at column 6")},
+		{"F   X", mapError(1, "unknown character 'X' at column 5")},
+		{"Fx", mapError(1, "unknown field key 'x' at column 2")},
+		{"F    .", mapError(1, "misaligned hex '.' at column 6")},
+		{"F     .", mapError(1, "misaligned hex '.' at column 7")},
+		{"F\n.", mapError(2, "misaligned hex '.' at column 1")},
+	}
+
+	for _, test := range tests {
+		_, errs := ParseMap(test.content)
+		if !slices.Contains(errs, test.err) {
+			t.Errorf("%q: errors %v, expected %v", test.content, errs, test.err)
+		}
+	}
+
+	_, errs := ParseMap("F   H0  B0\n  .   .   F2")
+	if len(errs) > 0 {
+		t.Errorf("valid map: errors %v", errs)
+	}
+}
+
+func TestValidateMap(t *testing.T) {
+	tests := []struct {
+		content string
+		players []int
+		errs    []MapError
+	}{
+		{"F   H0  B0  .   H3  B3", []int{2}, nil},
+		{"F   H0  B0  .   H3  .", []int{2}, []MapError{mapError(0, "player 3 has no bee")}},
+		{".   B0  B0  .   H3  B3", []int{2}, []MapError{mapError(0, "player 0 has no hive")}},
+		{"F   H0  B0  .   H2  B2", []int{2}, []MapError{
+			mapError(0, "player 3 has no hive"),
+			mapError(0, "player 3 has no bee"),
+		}},
+		{"F   H0  B0", nil, []MapError{
+			mapError(0, "player 1 has no hive"),
+			mapError(0, "player 5 has no bee"),
+		}},
+		{"F   H0  B0", []int{7}, []MapError{
+			mapError(0, "invalid number of players: 7"),
+			mapError(0, "map does not support any number of players"),
+		}},
+		{".   H0  B0  .   H3  B3", []int{2}, []MapError{mapError(0, "map has no field")}},
+		{"F   H0  B0  R   H3  B3", []int{2}, []MapError{
+			mapWarning(1, "hex at 0,8 is not connected to the rest of the map"),
+			mapError(1, "spawn at 0,8 cannot reach any field"),
+			mapError(1, "spawn at 0,10 cannot reach any field"),
+		}},
+		{"", nil, []MapError{mapError(0, "map is empty")}},
+	}
+
+	for _, test := range tests {
+		mapData, parseErrs := ParseMap(test.content)
+		if len(parseErrs) > 0 {
+			t.Fatalf("%q: parse errors %v", test.content, parseErrs)
+		}
+		mapData.Players = test.players
+
+		errs := ValidateMap(mapData)
+		if test.errs == nil && len(errs) > 0 {
+			t.Errorf("%q: errors %v, expected none", test.content, errs)
+		}
+		for _, err := range test.errs {
+			if !slices.Contains(errs, err) {
+				t.Errorf("%q: errors %v, expected %v", test.content, errs, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package common
+
+import "slices"
+
+type EndReason string
+
+const (
+	DEPLETED           EndReason = "DEPLETED"
+	STALLED            EndReason = "STALLED"
+	TURN_LIMIT_REACHED EndReason = "TURN_LIMIT_REACHED"
+	TARGET_REACHED     EndReason = "TARGET_REACHED"
+	HILL_CONTROLLED    EndReason = "HILL_CONTROLLED"
+	LAST_HIVE_STANDING EndReason = "LAST_HIVE_STANDING"
+	ELIMINATION        EndReason = "ELIMINATION"
+	ABORTED            EndReason = "ABORTED"
+)
+
+func (gs *GameState) playersWithHive() []int {
+	var players []int
+	for _, hex := range gs.Hexes {
+		if hex.Entity != nil && hex.Entity.Type == HIVE && !slices.Contains(players, hex.Entity.Player) {
+			players = append(players, hex.Entity.Player)
+		}
+	}
+	slices.Sort(players)
+	return players
+}
+
+// scoreHills gives a point to the player with most bees on hills, if there
+// is a single one.
+func (gs *GameState) scoreHills() {
+	if gs.Rules.Victory != KING_OF_THE_HILL {
+		return
+	}
+
+	held := make([]int, gs.NumPlayers)
+	for _, hex := range gs.Hexes {
+		if hex.Hill && hex.Entity != nil && hex.Entity.Type == BEE {
+			held[hex.Entity.Player]++
+		}
+	}
+
+	best := slices.Max(held)
+	leaders := []int{}
+	for player, count := range held {
+		if count == best {
+			leaders = append(leaders, player)
+		}
+	}
+
+	if best > 0 && len(leaders) == 1 {
+		gs.HillPoints[leaders[0]]++
+	}
+}
+
+// goalReached checks the specific goal of the victory condition
+func (gs *GameState) goalReached() EndReason {
+	switch gs.Rules.Victory {
+	case FIRST_TO_FLOWERS:
+		if slices.Max(gs.PlayerResources) >= gs.Rules.TargetFlowers {
+			return TARGET_REACHED
+		}
+	case KING_OF_THE_HILL:
+		if slices.Max(gs.HillPoints) >= gs.Rules.HillScore {
+			return HILL_CONTROLLED
+		}
+	case LAST_HIVE:
+		standing := len(gs.playersWithHive())
+		if standing == 0 || (standing == 1 && gs.NumPlayers > 1) {
+			return LAST_HIVE_STANDING
+		}
+	}
+	return ""
+}
+
+// gameStopped checks the conditions that end a game whatever its goal
+func (gs *GameState) gameStopped() EndReason {
+
+	// Everyone else eliminated
+
+	active := len(gs.activePlayers())
+	if active == 0 || (active == 1 && gs.NumPlayers > 1) {
+		return ELIMINATION
+	}
+
+	// Turn limit
+
+	if gs.Rules.TurnLimit > 0 && gs.Turn >= gs.Rules.TurnLimit {
+		return TURN_LIMIT_REACHED
+	}
+
+	if gs.Rules.Victory == TURN_LIMIT {
+		return ""
+	}
+
+	// No resources left (fields that regrow never run out)
+
+	var resourcesLeft uint
+	for _, hex := range gs.Hexes {
+		resourcesLeft += hex.Resources + hex.Flowers
+		if hex.Entity != nil && hex.Entity.HasFlower {
+			resourcesLeft++
+		}
+	}
+
+	if resourcesLeft == 0 && !gs.Rules.Regrowth() {
+		return DEPLETED
+	}
+
+	// No influence change in a while
+
+	if gs.Turn-gs.LastResourceChange > gs.Rules.ResourceTimeout {
+		return STALLED
+	}
+
+	return ""
+}
+
+func (gs *GameState) checkEndGame() {
+	reason := gs.goalReached()
+	if reason == "" {
+		reason = gs.gameStopped()
+	}
+	if reason == "" {
+		return
+	}
+
+	gs.GameOver = true
+	gs.EndReason = reason
+
+	// Determine winners: the best scores among the players still in the race
+
+	scores := gs.PlayerResources
+	if gs.Rules.Victory == KING_OF_THE_HILL {
+		scores = gs.HillPoints
+	}
+
+	contenders := gs.activePlayers()
+	if gs.Rules.Victory == LAST_HIVE {
+		contenders = gs.playersWithHive()
+	}
+	if len(contenders) == 0 {
+		for player := range gs.NumPlayers {
+			contenders = append(contenders, player)
+		}
+	}
+
+	var best uint
+	for _, player := range contenders {
+		best = max(best, scores[player])
+	}
+	for _, player := range contenders {
+		if scores[player] == best {
+			gs.Winners = append(gs.Winners, player)
+		}
+	}
+}
+
+// Abort ends the game right away, without winners
+func (gs *GameState) Abort() {
+	gs.GameOver = true
+	gs.EndReason = ABORTED
+	gs.Winners = nil
+}
+
+// Forfeit eliminates a player, even if they still have units on the map
+func (gs *GameState) Forfeit(player int) {
+	gs.Eliminated[player] = true
+}
+
+// Kick forfeits a player outside of a turn, and ends the game right away if
+// that decides it
+func (gs *GameState) Kick(player int) {
+	gs.Forfeit(player)
+	gs.checkEndGame()
+}
+
+func (gs *GameState) IsEliminated(player int) bool {
+	return player < len(gs.Eliminated) && gs.Eliminated[player]
+}
+
+// checkEliminations marks the players who have neither hives nor bees left.
+// Walls do not keep a player in the game.
+func (gs *GameState) checkEliminations() {
+	alive := make([]bool, gs.NumPlayers)
+	for _, hex := range gs.Hexes {
+		if hex.Entity != nil && hex.Entity.Type != WALL {
+			alive[hex.Entity.Player] = true
+		}
+	}
+
+	for player := range gs.NumPlayers {
+		if !alive[player] {
+			gs.Eliminated[player] = true
+		}
+	}
+}
+
+func (gs *GameState) activePlayers() []int {
+	var players []int
+	for player := range gs.NumPlayers {
+		if !gs.IsEliminated(player) {
+			players = append(players, player)
+		}
+	}
+	return players
+}
@@ -0,0 +1,103 @@
+package common
+
+import "math"
+
+// Line of sight is computed in cube coordinates, sampling the hexes along the
+// straight line between two centers:
+// https://www.redblobgames.com/grids/hexagons/#line-drawing
+
+type cube struct {
+	Q, R, S float64
+}
+
+func toCube(c Coords) cube {
+	q := float64(c.Col-c.Row) / 2
+	r := float64(c.Row)
+	return cube{q, r, -q - r}
+}
+
+func (a cube) lerp(b cube, t float64) cube {
+	return cube{
+		a.Q + (b.Q-a.Q)*t,
+		a.R + (b.R-a.R)*t,
+		a.S + (b.S-a.S)*t,
+	}
+}
+
+func (c cube) round() Coords {
+	q, r, s := math.Round(c.Q), math.Round(c.R), math.Round(c.S)
+	dq, dr, ds := math.Abs(q-c.Q), math.Abs(r-c.R), math.Abs(s-c.S)
+
+	if dq > dr && dq > ds {
+		q = -r - s
+	} else if dr > ds {
+		r = -q - s
+	}
+
+	return Coords{Row: int(r), Col: int(2*q + r)}
+}
+
+// lineOfSight tells whether no rock stands between two hexes. The hexes
+// themselves can be rocks.
+func (gs *GameState) lineOfSight(from, to Coords) bool {
+	n := from.Distance(to)
+
+	// Nudge the line so that it does not run exactly along hex edges
+	a := toCube(from)
+	a = cube{a.Q + 1e-6, a.R + 2e-6, a.S - 3e-6}
+	b := toCube(to)
+
+	for i := 1; i < n; i++ {
+		hex := gs.Hexes[a.lerp(b, float64(i)/float64(n)).round()]
+		if hex != nil && hex.Terrain == ROCK {
+			return false
+		}
+	}
+	return true
+}
+
+func (rules Rules) Sight(kind EntityType) int {
+	switch kind {
+	case HIVE:
+		return rules.HiveSight
+	case BEE:
+		return rules.BeeSight
+	case WALL:
+		return rules.WallSight
+	}
+	return 0
+}
+
+// InRange lists the coordinates within the given distance, including the
+// center itself
+func (c Coords) InRange(distance int) []Coords {
+	var result []Coords
+	for drow := -distance; drow <= distance; drow++ {
+		width := 2*distance - abs(drow)
+		for dcol := -width; dcol <= width; dcol += 2 {
+			result = append(result, Coords{Row: c.Row + drow, Col: c.Col + dcol})
+		}
+	}
+	return result
+}
+
+// VisibleHexes lists the hexes a player can currently see, by looking around
+// each of the player's entities
+func (gs *GameState) VisibleHexes(player int) map[Coords]bool {
+	visible := make(map[Coords]bool)
+	for from, hex := range gs.Hexes {
+		if hex.Entity == nil || hex.Entity.Player != player {
+			continue
+		}
+
+		for _, coords := range from.InRange(gs.Rules.Sight(hex.Entity.Type)) {
+			if visible[coords] || gs.Hexes[coords] == nil {
+				continue
+			}
+			if !gs.Rules.RockOcclusion || gs.lineOfSight(from, coords) {
+				visible[coords] = true
+			}
+		}
+	}
+	return visible
+}
@@ -0,0 +1,146 @@
+package common
+
+import (
+	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// visibleHexesFullScan is the former way of computing visibility: every hex
+// of the map is checked against every entity of the player.
+func (gs *GameState) visibleHexesFullScan(player int) map[Coords]bool {
+	visible := make(map[Coords]bool)
+	for coords := range gs.Hexes {
+		for from, hex := range gs.Hexes {
+			if hex.Entity != nil &&
+				hex.Entity.Player == player &&
+				from.Distance(coords) <= gs.Rules.Sight(hex.Entity.Type) &&
+				(!gs.Rules.RockOcclusion || gs.lineOfSight(from, coords)) {
+				visible[coords] = true
+				break
+			}
+		}
+	}
+	return visible
+}
+
+// benchmarkState loads a map, and spreads bees over it as in a busy game
+func benchmarkState(tb testing.TB, players int, bees int, occlusion bool) *GameState {
+	mapData, err := LoadMap("../maps/balanced.txt")
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	gs := NewGameState(mapData, players)
+	gs.Rules.RockOcclusion = occlusion
+
+	rng := rand.New(rand.NewSource(1))
+	coords := slices.SortedFunc(maps.Keys(gs.Hexes), func(a, b Coords) int {
+		if a.Row != b.Row {
+			return a.Row - b.Row
+		}
+		return a.Col - b.Col
+	})
+	rng.Shuffle(len(coords), func(i, j int) {
+		coords[i], coords[j] = coords[j], coords[i]
+	})
+
+	placed := 0
+	for _, c := range coords {
+		hex := gs.Hexes[c]
+		if placed == players*bees {
+			break
+		}
+		if hex.Entity == nil && hex.Terrain.IsWalkable() {
+			hex.Entity = gs.newEntity(BEE, placed%players)
+			placed++
+		}
+	}
+
+	return gs
+}
+
+func TestVisibleHexesMatchesFullScan(t *testing.T) {
+	for _, occlusion := range []bool{false, true} {
+		gs := benchmarkState(t, 4, 20, occlusion)
+		for player := range gs.NumPlayers {
+			got := gs.VisibleHexes(player)
+			want := gs.visibleHexesFullScan(player)
+			if !maps.Equal(got, want) {
+				t.Errorf("occlusion %v, player %d: %d visible hexes, expected %d", occlusion, player, len(got), len(want))
+			}
+		}
+	}
+}
+
+func BenchmarkVisibleHexes(b *testing.B) {
+	for _, occlusion := range []bool{false, true} {
+		gs := benchmarkState(b, 4, 20, occlusion)
+
+		b.Run(fmt.Sprintf("FullScan/occlusion=%v", occlusion), func(b *testing.B) {
+			for b.Loop() {
+				gs.visibleHexesFullScan(0)
+			}
+		})
+
+		b.Run(fmt.Sprintf("InRange/occlusion=%v", occlusion), func(b *testing.B) {
+			for b.Loop() {
+				gs.VisibleHexes(0)
+			}
+		})
+	}
+}
+
+// BenchmarkPlayerView builds the views of all the players of a turn, as the
+// server does once per turn
+func BenchmarkPlayerView(b *testing.B) {
+	for _, occlusion := range []bool{false, true} {
+		gs := benchmarkState(b, 4, 20, occlusion)
+
+		b.Run(fmt.Sprintf("FullScan/occlusion=%v", occlusion), func(b *testing.B) {
+			for b.Loop() {
+				for player := range gs.NumPlayers {
+					gs.viewOf(player, gs.visibleHexesFullScan(player))
+				}
+			}
+		})
+
+		b.Run(fmt.Sprintf("InRange/occlusion=%v", occlusion), func(b *testing.B) {
+			for b.Loop() {
+				for player := range gs.NumPlayers {
+					gs.PlayerView(player)
+				}
+			}
+		})
+	}
+}
+
+func TestRockOcclusion(t *testing.T) {
+	gs := openState()
+	from := Coords{Row: 3, Col: 1}
+	rock := Coords{Row: 3, Col: 5}
+	behind := Coords{Row: 3, Col: 9}
+	gs.Hexes[rock].Terrain = ROCK
+
+	if gs.lineOfSight(from, behind) {
+		t.Error("seeing through a rock")
+	}
+	if !gs.lineOfSight(from, rock) || !gs.lineOfSight(rock, behind) {
+		t.Error("rocks at the ends of the line should not block it")
+	}
+	if !gs.lineOfSight(from, Coords{Row: 1, Col: 9}) {
+		t.Error("line past the rock blocked")
+	}
+
+	gs.Rules.RockOcclusion = true
+	gs.Rules.BeeSight = 4
+	gs.Hexes[from].Entity = &Entity{Type: BEE, Player: 0}
+	gs.Hexes[Coords{Row: 0, Col: 0}].Entity = nil
+
+	visible := gs.VisibleHexes(0)
+	if !visible[rock] || visible[behind] || !visible[Coords{Row: 3, Col: 3}] {
+		t.Errorf("rock visible %v, hex behind it visible %v", visible[rock], visible[behind])
+	}
+}
@@ -14,8 +14,18 @@ import (
 )
 
 func request(url string) string {
+	return requestWithKey(url, "")
+}
+
+// requestWithKey sends the API key of a registered team, if there is one
+func requestWithKey(url string, key string) string {
 
-	resp, err := http.Get(url)
+	req, _ := http.NewRequest("GET", url, nil)
+	if key != "" {
+		req.Header.Set("X-Api-Key", key)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		fmt.Println("Could not get " + url)
 		os.Exit(1)
@@ -46,7 +56,7 @@ type WebSocketMessage struct {
 func joinGame(host string, id string, name string) JoinResponse {
 
 	url := "http://" + host + fmt.Sprintf("/join?id=%s&name=%s", id, name)
-	body := request(url)
+	body := requestWithKey(url, os.Getenv("ARENA_KEY"))
 
 	var response JoinResponse
 	json.Unmarshal([]byte(body), &response)
@@ -3,17 +3,18 @@ local json = require "lunajson"
 local function req(host, route, method, payload)
 
 	local http = require "http.request"
-	local httph = require "http.headers"
 
-	local headers
+	local req = http.new_from_uri("http://" .. host .. route)
 
 	if method == "POST" then
-		headers = httph.new()
-		headers:append(":method", "POST")
-		headers:append("content-type", "application/json")
+		req.headers:upsert(":method", "POST")
+		req.headers:upsert("content-type", "application/json")
 	end
 
-	local req = http.new_from_uri("http://" .. host .. route, headers)
+	local key = os.getenv("ARENA_KEY")
+	if key then
+		req.headers:upsert("x-api-key", key)
+	end
 	if payload then
 		req:set_body(json.encode(payload))
 	end
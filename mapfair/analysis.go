@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	. "hive-arena/common"
+)
+
+type Settings struct {
+	Reach     int
+	Margin    int
+	Threshold float64
+}
+
+type PlayerStats struct {
+	Player, Slot  int
+	FieldDistance int
+	Flowers       uint
+	Closest       int
+	Contested     int
+	Chokepoints   int
+}
+
+type Asymmetry struct {
+	Metric   string
+	Min, Max float64
+}
+
+type Report struct {
+	NumPlayers int
+	Players    []PlayerStats
+	Flags      []Asymmetry
+}
+
+const unreachable = 1 << 30
+
+func distanceOr(dist map[Coords]int, coords Coords) int {
+	d, found := dist[coords]
+	if !found {
+		return unreachable
+	}
+	return d
+}
+
+// chokepoints finds the articulation points of the walkable terrain: the hexes
+// which, if walled off, split the map in two.
+func chokepoints(mapData MapData) map[Coords]bool {
+	index := make(map[Coords]int)
+	low := make(map[Coords]int)
+	points := make(map[Coords]bool)
+	counter := 0
+
+	var visit func(c Coords, parent *Coords)
+	visit = func(c Coords, parent *Coords) {
+		counter++
+		index[c] = counter
+		low[c] = counter
+		children := 0
+
+		for _, n := range c.Neighbours() {
+			if !mapData.Map[n].IsWalkable() || (parent != nil && n == *parent) {
+				continue
+			}
+			if _, seen := index[n]; seen {
+				low[c] = min(low[c], index[n])
+				continue
+			}
+
+			children++
+			visit(n, &c)
+			low[c] = min(low[c], low[n])
+
+			if parent != nil && low[n] >= index[c] {
+				points[c] = true
+			}
+		}
+
+		if parent == nil && children > 1 {
+			points[c] = true
+		}
+	}
+
+	for coords, terrain := range mapData.Map {
+		if _, seen := index[coords]; !seen && terrain.IsWalkable() {
+			visit(coords, nil)
+		}
+	}
+
+	return points
+}
+
+func Analyze(mapData MapData, numPlayers int, settings Settings) Report {
+	mapping := mapData.PlayerMapping(numPlayers)
+
+	var fields []Coords
+	for coords, terrain := range mapData.Map {
+		if terrain == FIELD {
+			fields = append(fields, coords)
+		}
+	}
+
+	// Distances from the hives of each active slot
+
+	dists := make([]map[Coords]int, numPlayers)
+	slots := make([]int, numPlayers)
+	for slot, player := range mapping {
+		if player == -1 {
+			continue
+		}
+
+		var hives []Coords
+		for _, spawn := range mapData.Spawns {
+			if spawn.Player == slot && spawn.Kind == HIVE {
+				hives = append(hives, spawn.Coords)
+			}
+		}
+
+		slots[player] = slot
+		dists[player] = mapData.Distances(hives)
+	}
+
+	chokes := chokepoints(mapData)
+	report := Report{NumPlayers: numPlayers}
+
+	for player, dist := range dists {
+		stats := PlayerStats{Player: player, Slot: slots[player], FieldDistance: unreachable}
+
+		for _, field := range fields {
+			d := distanceOr(dist, field)
+			stats.FieldDistance = min(stats.FieldDistance, d)
+
+			if d <= settings.Reach {
+				stats.Flowers += mapData.InitialFlowers(field)
+			}
+
+			if d == unreachable {
+				continue
+			}
+
+			closestOther := unreachable
+			for other, otherDist := range dists {
+				if other != player {
+					closestOther = min(closestOther, distanceOr(otherDist, field))
+				}
+			}
+
+			if abs(d-closestOther) <= settings.Margin {
+				stats.Contested++
+			} else if d < closestOther {
+				stats.Closest++
+			}
+		}
+
+		for coords := range chokes {
+			if distanceOr(dist, coords) <= settings.Reach {
+				stats.Chokepoints++
+			}
+		}
+
+		report.Players = append(report.Players, stats)
+	}
+
+	if numPlayers > 1 {
+		report.flag("distance to nearest field", settings.Threshold, func(s PlayerStats) float64 { return float64(s.FieldDistance) })
+		report.flag(fmt.Sprintf("flowers within %d moves", settings.Reach), settings.Threshold, func(s PlayerStats) float64 { return float64(s.Flowers) })
+		report.flag("uncontested closest fields", settings.Threshold, func(s PlayerStats) float64 { return float64(s.Closest) })
+		report.flag("contested fields", settings.Threshold, func(s PlayerStats) float64 { return float64(s.Contested) })
+		report.flag(fmt.Sprintf("chokepoints within %d moves", settings.Reach), settings.Threshold, func(s PlayerStats) float64 { return float64(s.Chokepoints) })
+	}
+
+	return report
+}
+
+func abs(x int) int {
+	return max(x, -x)
+}
+
+// flag records an asymmetry when the spread of a metric across players,
+// relative to its largest value, exceeds the threshold.
+func (report *Report) flag(metric string, threshold float64, value func(PlayerStats) float64) {
+	var values []float64
+	for _, stats := range report.Players {
+		values = append(values, value(stats))
+	}
+
+	lo, hi := slices.Min(values), slices.Max(values)
+	if hi > 0 && (hi-lo)/hi > threshold {
+		report.Flags = append(report.Flags, Asymmetry{metric, lo, hi})
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "hive-arena/common"
+)
+
+func formatDistance(d int) string {
+	if d == unreachable {
+		return "-"
+	}
+	return fmt.Sprint(d)
+}
+
+func printReport(name string, report Report) {
+	fmt.Printf("%s, %d players\n", name, report.NumPlayers)
+	fmt.Printf("  %-6s %-4s %-10s %-8s %-7s %-9s %s\n",
+		"player", "slot", "field dist", "flowers", "closest", "contested", "chokepoints")
+
+	for _, stats := range report.Players {
+		fmt.Printf("  %-6d %-4d %-10s %-8d %-7d %-9d %d\n",
+			stats.Player,
+			stats.Slot,
+			formatDistance(stats.FieldDistance),
+			stats.Flowers,
+			stats.Closest,
+			stats.Contested,
+			stats.Chokepoints,
+		)
+	}
+
+	for _, flag := range report.Flags {
+		fmt.Printf("  ! %s ranges from %g to %g\n", flag.Metric, flag.Min, flag.Max)
+	}
+
+	fmt.Println()
+}
+
+func main() {
+	var settings Settings
+	flag.IntVar(&settings.Reach, "reach", 10, "number of moves from the hive considered for flowers and chokepoints")
+	flag.IntVar(&settings.Margin, "margin", 1, "distance difference under which a field counts as contested")
+	flag.Float64Var(&settings.Threshold, "threshold", 0.15, "relative difference between players above which a metric is flagged")
+	players := flag.Int("players", 0, "only analyze games with this number of players")
+
+	flag.Usage = func() {
+		fmt.Println("Usage: mapfair [options] <map file>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	fair := true
+
+	for _, path := range flag.Args() {
+		mapData, err := LoadMap(path)
+		if err != nil {
+			fmt.Printf("%s: %s\n", path, err)
+			os.Exit(1)
+		}
+
+		name := filepath.Base(path)
+
+		for numPlayers := 2; numPlayers <= MAX_PLAYERS; numPlayers++ {
+			if !mapData.SupportsNumPlayers(numPlayers) || (*players != 0 && numPlayers != *players) {
+				continue
+			}
+
+			report := Analyze(mapData, numPlayers, settings)
+			printReport(name, report)
+
+			if len(report.Flags) > 0 {
+				fair = false
+			}
+		}
+	}
+
+	if !fair {
+		os.Exit(1)
+	}
+}
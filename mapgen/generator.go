@@ -0,0 +1,461 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+
+	. "hive-arena/common"
+)
+
+// The generator works in axial coordinates centered on the middle of the map,
+// which makes rotations and mirroring simple:
+// https://www.redblobgames.com/grids/hexagons/#rotation
+
+type Axial struct {
+	Q, R int
+}
+
+func (a Axial) Coords() Coords {
+	return Coords{Row: a.R, Col: 2*a.Q + a.R}
+}
+
+func (a Axial) Rotate() Axial {
+	return Axial{-a.R, a.Q + a.R}
+}
+
+func (a Axial) Mirror() Axial {
+	return Axial{-a.Q - a.R, a.R}
+}
+
+func (a Axial) Neighbours() []Axial {
+	return []Axial{
+		{a.Q + 1, a.R}, {a.Q + 1, a.R - 1}, {a.Q, a.R - 1},
+		{a.Q - 1, a.R}, {a.Q - 1, a.R + 1}, {a.Q, a.R + 1},
+	}
+}
+
+func compareAxial(a, b Axial) int {
+	if a.R != b.R {
+		return a.R - b.R
+	}
+	return a.Q - b.Q
+}
+
+type Config struct {
+	Shape         string
+	Width, Height int
+	Radius        int
+	Symmetry      string
+	SpawnDistance int
+	Bees          int
+	RockDensity   float64
+	FieldClusters int
+	FieldSize     int
+	Seed          int64
+
+	// The numbers of players the map is made for, all the ones the symmetry
+	// fits if empty
+	Players []int
+}
+
+type Generator struct {
+	config  Config
+	rng     *rand.Rand
+	region  []Axial
+	inside  map[Axial]bool
+	terrain map[Axial]Terrain
+	spawns  map[Axial]Spawn
+	clear   map[Axial]bool
+}
+
+func (gen *Generator) inShape(a Axial) bool {
+	switch gen.config.Shape {
+	case "hex":
+		return max(abs(a.Q), abs(a.R), abs(a.Q+a.R)) <= gen.config.Radius
+	case "rect":
+		c := a.Coords()
+		return abs(c.Row) <= gen.config.Height/2 && abs(c.Col) <= gen.config.Width-1
+	}
+	return false
+}
+
+// symmetries lists the transformations of the chosen symmetry, starting with
+// the identity
+func (gen *Generator) symmetries() []func(Axial) Axial {
+	result := []func(Axial) Axial{func(a Axial) Axial { return a }}
+
+	switch gen.config.Symmetry {
+	case "mirror":
+		result = append(result, Axial.Mirror)
+	case "2", "3", "6":
+		step := map[string]int{"2": 3, "3": 2, "6": 1}[gen.config.Symmetry]
+		for turns := step; turns < 6; turns += step {
+			result = append(result, func(a Axial) Axial {
+				for range turns {
+					a = a.Rotate()
+				}
+				return a
+			})
+		}
+	}
+
+	return result
+}
+
+// orbit lists all the hexes a given hex is mapped to by the chosen symmetry,
+// including itself.
+func (gen *Generator) orbit(a Axial) []Axial {
+	var result []Axial
+	for _, transform := range gen.symmetries() {
+		if b := transform(a); !slices.Contains(result, b) {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+func (gen *Generator) set(a Axial, terrain Terrain) {
+	for _, b := range gen.orbit(a) {
+		if gen.inside[b] && !gen.clear[b] {
+			gen.terrain[b] = terrain
+		}
+	}
+}
+
+func (gen *Generator) buildRegion() {
+	gen.inside = make(map[Axial]bool)
+	gen.region = nil
+
+	size := max(gen.config.Radius, gen.config.Width, gen.config.Height)
+	for r := -size; r <= size; r++ {
+		for q := -2 * size; q <= 2*size; q++ {
+			a := Axial{q, r}
+			if !gen.inShape(a) {
+				continue
+			}
+
+			// Only keep hexes whose symmetric images are also in the map
+			keep := true
+			for _, b := range gen.orbit(a) {
+				keep = keep && gen.inShape(b)
+			}
+
+			if keep {
+				gen.inside[a] = true
+				gen.region = append(gen.region, a)
+			}
+		}
+	}
+
+	slices.SortFunc(gen.region, compareAxial)
+}
+
+// hive gives the position of the hive of a spawn slot. The six slots are at
+// regular angles around the center, starting with slot 0 on the west side, like
+// the hand-made maps.
+func (gen *Generator) hive(slot int) Axial {
+	hive := Axial{-gen.config.SpawnDistance, 0}
+	for range slot {
+		hive = hive.Rotate()
+	}
+	return hive
+}
+
+// fitsPlayers tells whether the symmetry makes the spawn slots used by games
+// with n players equivalent: some of its transformations keep the set of used
+// slots, and take any of them to all the others.
+func (gen *Generator) fitsPlayers(n int) bool {
+	var used []Axial
+	for slot, player := range (MapData{}).PlayerMapping(n) {
+		if player != -1 {
+			used = append(used, gen.hive(slot))
+		}
+	}
+
+	reached := []Axial{}
+	for _, transform := range gen.symmetries() {
+		keeps := true
+		for _, a := range used {
+			keeps = keeps && slices.Contains(used, transform(a))
+		}
+		if b := transform(used[0]); keeps && !slices.Contains(reached, b) {
+			reached = append(reached, b)
+		}
+	}
+
+	return len(reached) == len(used)
+}
+
+// numPlayers checks the numbers of players asked for against the symmetry, or
+// finds all the numbers it fits. Maps without symmetry can be played with any
+// number of players, with no guarantee of fairness.
+func (gen *Generator) numPlayers() ([]int, error) {
+	if len(gen.config.Players) == 0 {
+		var players []int
+		for n := 1; n <= MAX_PLAYERS; n++ {
+			if gen.config.Symmetry == "1" || gen.fitsPlayers(n) {
+				players = append(players, n)
+			}
+		}
+		return players, nil
+	}
+
+	for _, n := range gen.config.Players {
+		if !IsValidNumPlayers(n) {
+			return nil, fmt.Errorf("invalid number of players: %d", n)
+		}
+		if gen.config.Symmetry != "1" && !gen.fitsPlayers(n) {
+			return nil, fmt.Errorf("symmetry %s does not make the spawn slots of %d player games equivalent", gen.config.Symmetry, n)
+		}
+	}
+	return slices.Sorted(slices.Values(gen.config.Players)), nil
+}
+
+// placeSpawns puts the hive and the bees of each of the six slots
+func (gen *Generator) placeSpawns() error {
+	gen.spawns = make(map[Axial]Spawn)
+	gen.clear = make(map[Axial]bool)
+
+	hive := gen.hive(0)
+	bees := []Axial{{hive.Q + 1, hive.R}, {hive.Q + 1, hive.R - 1}, {hive.Q, hive.R + 1}}[:gen.config.Bees]
+
+	for slot := range MAX_PLAYERS {
+		positions := append([]Axial{hive}, bees...)
+		for i, a := range positions {
+			if !gen.inside[a] {
+				return fmt.Errorf("spawn for player %d falls outside of the map, try a shorter spawn distance", slot)
+			}
+
+			kind := BEE
+			if i == 0 {
+				kind = HIVE
+			}
+			gen.spawns[a] = Spawn{Kind: kind, Player: slot, Coords: a.Coords()}
+
+			gen.clear[a] = true
+			for _, n := range a.Neighbours() {
+				gen.clear[n] = true
+			}
+		}
+
+		hive = hive.Rotate()
+		for i := range bees {
+			bees[i] = bees[i].Rotate()
+		}
+	}
+
+	return nil
+}
+
+func (gen *Generator) randomHex() Axial {
+	return gen.region[gen.rng.Intn(len(gen.region))]
+}
+
+func (gen *Generator) randomWalk(start Axial, length int, terrain Terrain, accept func(Axial) bool) {
+	current := start
+	for range length {
+		if accept(current) {
+			gen.set(current, terrain)
+		}
+
+		neighbours := current.Neighbours()
+		next := neighbours[gen.rng.Intn(len(neighbours))]
+		if gen.inside[next] {
+			current = next
+		}
+	}
+}
+
+func (gen *Generator) count(terrain Terrain) int {
+	total := 0
+	for _, a := range gen.region {
+		if gen.terrain[a] == terrain {
+			total++
+		}
+	}
+	return total
+}
+
+func (gen *Generator) fillTerrain() {
+	gen.terrain = make(map[Axial]Terrain)
+	for _, a := range gen.region {
+		gen.terrain[a] = EMPTY
+	}
+
+	// Rocks, as short random lines
+
+	rocks := int(gen.config.RockDensity * float64(len(gen.region)))
+	for attempts := 0; gen.count(ROCK) < rocks && attempts < 10*len(gen.region); attempts++ {
+		gen.randomWalk(gen.randomHex(), 1+gen.rng.Intn(5), ROCK, func(Axial) bool { return true })
+	}
+
+	// Field clusters, grown around a random center
+
+	for range gen.config.FieldClusters {
+		gen.randomWalk(gen.randomHex(), gen.config.FieldSize, FIELD, func(a Axial) bool {
+			return gen.terrain[a] != ROCK
+		})
+	}
+
+	// Turn anything that cannot be reached from the spawns into rock
+
+	reached := map[Axial]bool{}
+	queue := []Axial{}
+	for _, a := range gen.region {
+		if _, found := gen.spawns[a]; found {
+			reached[a] = true
+			queue = append(queue, a)
+		}
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, n := range current.Neighbours() {
+			if gen.inside[n] && !reached[n] && gen.terrain[n].IsWalkable() {
+				reached[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+
+	for _, a := range gen.region {
+		if !reached[a] {
+			gen.terrain[a] = ROCK
+		}
+	}
+}
+
+func (gen *Generator) mapData() MapData {
+	mapData := MapData{Map: make(map[Coords]Terrain), Rules: DefaultRules()}
+
+	for _, a := range gen.region {
+		mapData.Map[a.Coords()] = gen.terrain[a]
+		if spawn, found := gen.spawns[a]; found {
+			mapData.Spawns = append(mapData.Spawns, spawn)
+		}
+	}
+
+	return mapData
+}
+
+func Generate(config Config) (MapData, error) {
+	gen := &Generator{
+		config: config,
+		rng:    rand.New(rand.NewSource(config.Seed)),
+	}
+
+	players, err := gen.numPlayers()
+	if err != nil {
+		return MapData{}, err
+	}
+
+	gen.buildRegion()
+	if len(gen.region) == 0 {
+		return MapData{}, fmt.Errorf("map is empty")
+	}
+
+	err = gen.placeSpawns()
+	if err != nil {
+		return MapData{}, err
+	}
+
+	var errs []MapError
+	for range 100 {
+		gen.fillTerrain()
+
+		mapData := gen.mapData()
+		mapData.Players = players
+		errs = ValidateMap(mapData)
+		if len(errs) == 0 {
+			return mapData, nil
+		}
+	}
+
+	return MapData{}, fmt.Errorf("could not generate a valid map: %s", errs[0])
+}
+
+var spawnChars = map[EntityType]string{
+	HIVE: "H",
+	BEE:  "B",
+}
+
+var terrainChars = map[Terrain]string{
+	EMPTY: ".",
+	FIELD: "F",
+	ROCK:  "R",
+}
+
+// Format writes the map in the text format of the maps directory: each hex
+// takes two characters, and the column of a hex in the doubled coordinates
+// system gives its position on the line.
+func Format(mapData MapData) string {
+	minRow, minCol := 1<<30, 1<<30
+	maxRow := -minRow
+	for coords := range mapData.Map {
+		minRow = min(minRow, coords.Row)
+		maxRow = max(maxRow, coords.Row)
+		minCol = min(minCol, coords.Col)
+	}
+
+	// Keep row + col even, as the doubled coordinates require
+	if (minRow+minCol)%2 != 0 {
+		minCol--
+	}
+
+	lines := make([][]byte, maxRow-minRow+1)
+	put := func(coords Coords, token string) {
+		line := &lines[coords.Row-minRow]
+		pos := 2 * (coords.Col - minCol)
+		for len(*line) < pos+len(token) {
+			*line = append(*line, ' ')
+		}
+		copy((*line)[pos:], token)
+	}
+
+	for coords, terrain := range mapData.Map {
+		put(coords, terrainChars[terrain])
+	}
+	for _, spawn := range mapData.Spawns {
+		put(spawn.Coords, fmt.Sprintf("%s%d", spawnChars[spawn.Kind], spawn.Player))
+	}
+
+	var builder strings.Builder
+	for _, line := range lines {
+		builder.Write(line)
+		builder.WriteString("\n")
+	}
+
+	return builder.String()
+}
+
+// generatedMap is the part of the JSON map format written by the generator
+type generatedMap struct {
+	Description string   `json:"description"`
+	Players     []int    `json:"players"`
+	Map         []string `json:"map"`
+}
+
+// FormatJSON writes the map in the JSON map format, which also gives the
+// numbers of players the map is made for.
+func FormatJSON(mapData MapData, description string) ([]byte, error) {
+	file := generatedMap{
+		Description: description,
+		Players:     mapData.Players,
+		Map:         strings.Split(strings.TrimSuffix(Format(mapData), "\n"), "\n"),
+	}
+
+	data, err := json.MarshalIndent(file, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func abs(x int) int {
+	return max(x, -x)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	. "hive-arena/common"
+)
+
+func testConfig(shape string, symmetry string, seed int64) Config {
+	return Config{
+		Shape:         shape,
+		Width:         30,
+		Height:        25,
+		Radius:        12,
+		Symmetry:      symmetry,
+		SpawnDistance: 8,
+		Bees:          3,
+		RockDensity:   0.1,
+		FieldClusters: 6,
+		FieldSize:     6,
+		Seed:          seed,
+	}
+}
+
+func TestGenerateValidMaps(t *testing.T) {
+	for _, shape := range []string{"hex", "rect"} {
+		for _, symmetry := range []string{"1", "2", "3", "6", "mirror"} {
+			for seed := int64(1); seed <= 3; seed++ {
+				mapData, err := Generate(testConfig(shape, symmetry, seed))
+				if err != nil {
+					t.Errorf("%s map with symmetry %s, seed %d: %s", shape, symmetry, seed, err)
+					continue
+				}
+				if errs := ValidateMap(mapData); len(errs) > 0 {
+					t.Errorf("%s map with symmetry %s, seed %d: %s", shape, symmetry, seed, errs[0])
+				}
+			}
+		}
+	}
+}
+
+func TestSymmetryFitsPlayers(t *testing.T) {
+	expected := map[string][]int{
+		"1":      {1, 2, 3, 4, 5, 6},
+		"2":      {1, 2},
+		"3":      {1, 3},
+		"6":      {1, 2, 3, 6},
+		"mirror": {1, 2},
+	}
+
+	for symmetry, players := range expected {
+		mapData, err := Generate(testConfig("hex", symmetry, 1))
+		if err != nil || !slices.Equal(mapData.Players, players) {
+			t.Errorf("symmetry %s: players %v, expected %v (%v)", symmetry, mapData.Players, players, err)
+		}
+	}
+
+	config := testConfig("hex", "3", 1)
+	config.Players = []int{2, 3}
+	if _, err := Generate(config); err == nil {
+		t.Error("2 player map generated with symmetry 3")
+	}
+
+	config.Players = []int{3}
+	mapData, err := Generate(config)
+	if err != nil || !slices.Equal(mapData.Players, []int{3}) {
+		t.Errorf("3 player map with symmetry 3: players %v (%v)", mapData.Players, err)
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	mapData, err := Generate(testConfig("hex", "3", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Formatting moves the map to the top left corner, so the maps are compared
+	// once formatted again
+	text, errs := ParseMap(Format(mapData))
+	if len(errs) > 0 || Format(text) != Format(mapData) {
+		t.Errorf("text map differs after parsing: %v", errs)
+	}
+
+	data, err := FormatJSON(mapData, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, errs := ParseMapJSON(data)
+	if len(errs) > 0 || Format(parsed) != Format(mapData) || !slices.Equal(parsed.Players, []int{1, 3}) {
+		t.Errorf("JSON map differs after parsing: players %v, %v", parsed.Players, errs)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	. "hive-arena/common"
+)
+
+func main() {
+	var config Config
+	flag.StringVar(&config.Shape, "shape", "hex", "shape of the map: hex or rect")
+	flag.IntVar(&config.Radius, "radius", 12, "radius of a hex map")
+	flag.IntVar(&config.Width, "width", 30, "number of hexes per row of a rect map")
+	flag.IntVar(&config.Height, "height", 25, "number of rows of a rect map")
+	flag.StringVar(&config.Symmetry, "symmetry", "6", "symmetry of the map: 1 (none), 2, 3 or 6 (rotations), or mirror")
+	players := flag.String("players", "", "comma separated numbers of players the map is made for (default: all the ones the symmetry fits)")
+	flag.IntVar(&config.SpawnDistance, "spawn", 0, "distance from the center of the map to the hives (default: two thirds of the map size)")
+	flag.IntVar(&config.Bees, "bees", 3, "number of bees per player, from 1 to 3")
+	flag.Float64Var(&config.RockDensity, "rocks", 0.1, "proportion of the map covered by rocks")
+	flag.IntVar(&config.FieldClusters, "fields", 6, "number of flower field clusters (before symmetry)")
+	flag.IntVar(&config.FieldSize, "fieldsize", 6, "size of each field cluster")
+	flag.Int64Var(&config.Seed, "seed", 0, "random seed, to generate the same map again (default: random)")
+	output := flag.String("o", "", "file to write the map to, in the JSON format if it ends with .json (default: standard output, in the text format)")
+	flag.Parse()
+
+	if config.Shape != "hex" && config.Shape != "rect" {
+		fmt.Println("Invalid shape: " + config.Shape)
+		os.Exit(2)
+	}
+
+	if !slices.Contains([]string{"1", "2", "3", "6", "mirror"}, config.Symmetry) {
+		fmt.Println("Invalid symmetry: " + config.Symmetry)
+		os.Exit(2)
+	}
+
+	if *players != "" {
+		for _, text := range strings.Split(*players, ",") {
+			n, err := strconv.Atoi(text)
+			if err != nil {
+				fmt.Println("Invalid number of players: " + text)
+				os.Exit(2)
+			}
+			config.Players = append(config.Players, n)
+		}
+	}
+
+	asJSON := filepath.Ext(*output) == ".json"
+	if *players != "" && !asJSON {
+		fmt.Println("Text maps cannot restrict the numbers of players, -players needs a .json output file")
+		os.Exit(2)
+	}
+
+	if config.Bees < 1 || config.Bees > 3 {
+		fmt.Printf("Invalid number of bees: %d\n", config.Bees)
+		os.Exit(2)
+	}
+
+	if config.SpawnDistance == 0 {
+		size := config.Radius
+		if config.Shape == "rect" {
+			size = min(config.Width, config.Height) / 2
+		}
+		config.SpawnDistance = max(1, 2*size/3)
+	}
+
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+	fmt.Fprintf(os.Stderr, "seed: %d\n", config.Seed)
+
+	mapData, err := Generate(config)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var data []byte
+	if asJSON {
+		description := fmt.Sprintf("Generated by mapgen, with seed %d and symmetry %s", config.Seed, config.Symmetry)
+		data, err = FormatJSON(mapData, description)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	} else {
+		data = []byte(Format(mapData))
+		if len(mapData.Players) < MAX_PLAYERS {
+			var counts []string
+			for _, n := range mapData.Players {
+				counts = append(counts, strconv.Itoa(n))
+			}
+			fmt.Fprintf(os.Stderr, "warning: symmetry %s only fits games with %s players, which text maps cannot record; use a .json output file to restrict them\n",
+				config.Symmetry, strings.Join(counts, ", "))
+		}
+	}
+
+	if *output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+
+	err = os.WriteFile(*output, data, 0644)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	. "hive-arena/common"
+)
+
+var strict bool
+
+func lint(path string) bool {
+	_, errs, err := ReadMap(path)
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	ok := true
+	for _, err := range errs {
+		prefix := "error"
+		if err.Warning {
+			prefix = "warning"
+		}
+		if !err.Warning || strict {
+			ok = false
+		}
+
+		if err.Line == 0 {
+			fmt.Printf("%s: %s: %s\n", path, prefix, err.Message)
+		} else {
+			fmt.Printf("%s:%d: %s: %s\n", path, err.Line, prefix, err.Message)
+		}
+	}
+
+	return ok
+}
+
+func main() {
+	flag.Usage = func() {
+		fmt.Println("Usage: maplint <map file>...")
+		flag.PrintDefaults()
+	}
+	flag.BoolVar(&strict, "strict", false, "treat warnings as errors")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ok := true
+	for _, path := range flag.Args() {
+		if !lint(path) {
+			ok = false
+		}
+	}
+
+	if !ok {
+		os.Exit(1)
+	}
+}
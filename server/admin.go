@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+type AdminAction string
+
+const (
+	PAUSE  AdminAction = "pause"
+	RESUME AdminAction = "resume"
+	STEP   AdminAction = "step"
+	ABORT  AdminAction = "abort"
+	KICK   AdminAction = "kick"
+)
+
+type adminMessage struct {
+	action AdminAction
+	player int
+	reply  chan error
+}
+
+// Admin applies an admin action to the session. The player is only used to
+// kick a player.
+func (session *GameSession) Admin(action AdminAction, player int) error {
+	reply := make(chan error, 1)
+	if !send(session, session.admin, adminMessage{action, player, reply}) {
+		return fmt.Errorf("Game is over")
+	}
+	return <-reply
+}
+
+func (session *GameSession) applyAdminAction(action AdminAction, player int) error {
+	if !session.isFull() {
+		return fmt.Errorf("Game has not started")
+	}
+	if session.State.GameOver {
+		return fmt.Errorf("Game is over")
+	}
+
+	switch action {
+	case PAUSE:
+		if session.Paused {
+			return fmt.Errorf("Game is already paused")
+		}
+		session.Paused = true
+		session.Clock.pause()
+		session.turnTimeout = nil
+		session.turnStart = nil
+
+	case RESUME:
+		if !session.Paused {
+			return fmt.Errorf("Game is not paused")
+		}
+		session.Paused = false
+		session.Clock.resume()
+
+		if session.betweenTurns {
+			session.turnStart = time.After(session.Timing.MinTurnDuration)
+		} else if session.allPlayed() {
+			session.processTurn()
+		} else {
+			session.scheduleDeadline()
+		}
+
+	case STEP:
+		if !session.Paused {
+			return fmt.Errorf("Game is not paused")
+		}
+		if !session.betweenTurns {
+			session.processTurn()
+		}
+		session.beginTurn()
+
+	case ABORT:
+		session.State.Abort()
+		session.endByAdmin()
+
+	case KICK:
+		if player < 0 || player >= session.NumPlayers {
+			return fmt.Errorf("Invalid player: %d", player)
+		}
+		if session.State.IsEliminated(player) {
+			return fmt.Errorf("Player has been eliminated")
+		}
+		session.State.Kick(player)
+
+		if session.State.GameOver {
+			session.endByAdmin()
+		} else if !session.betweenTurns && !session.Paused && session.allPlayed() {
+			session.processTurn()
+		}
+
+	default:
+		return fmt.Errorf("Invalid action: %s", action)
+	}
+
+	log.Printf("Admin action %s in game %s", action, session.ID)
+	return nil
+}
+
+// endByAdmin records a game ended by an admin action rather than by a
+// turn, and tells everyone about it
+func (session *GameSession) endByAdmin() {
+	session.History[len(session.History)-1].State = session.State.Clone()
+	session.persist()
+
+	session.Paused = false
+	session.betweenTurns = false
+	session.turnTimeout = nil
+	session.turnStart = nil
+
+	session.publishSnapshot()
+	session.notifySockets()
+}
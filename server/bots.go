@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"slices"
+
+	. "hive-arena/common"
+)
+
+// A Bot plays a player from inside the server. It gets the same view of the
+// game as remote agents, and gives its orders for the turn.
+type Bot func(view *GameState, player int) []*Order
+
+var Bots = map[string]Bot{
+	"random": randomBot,
+	"greedy": greedyBot,
+	"rusher": rusherBot,
+}
+
+const DefaultBot = "random"
+
+var directions = []Direction{E, SE, SW, W, NW, NE}
+
+type unit struct {
+	Coords Coords
+	Entity *Entity
+}
+
+func ownUnits(view *GameState, player int, kind EntityType) []unit {
+	var units []unit
+	for coords, hex := range view.Hexes {
+		if hex.Entity != nil && hex.Entity.Player == player && hex.Entity.Type == kind {
+			units = append(units, unit{coords, hex.Entity})
+		}
+	}
+	return units
+}
+
+func isFree(view *GameState, coords Coords) bool {
+	hex := view.Hexes[coords]
+	return hex != nil && hex.Terrain.IsWalkable() && hex.Entity == nil
+}
+
+// stepTowards finds the first step of a shortest path, through the visible
+// free hexes, to a hex that satisfies the goal
+func stepTowards(view *GameState, from Coords, goal func(Coords) bool) (Direction, bool) {
+	firstStep := map[Coords]Direction{}
+	queue := []Coords{}
+
+	for _, dir := range directions {
+		next := from.Neighbour(dir)
+		if isFree(view, next) {
+			firstStep[next] = dir
+			queue = append(queue, next)
+		}
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if goal(current) {
+			return firstStep[current], true
+		}
+
+		for _, next := range current.Neighbours() {
+			if _, seen := firstStep[next]; !seen && next != from && isFree(view, next) {
+				firstStep[next] = firstStep[current]
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	return "", false
+}
+
+func randomMove(view *GameState, from Coords) *Order {
+	var free []Direction
+	for _, dir := range directions {
+		if isFree(view, from.Neighbour(dir)) {
+			free = append(free, dir)
+		}
+	}
+	if len(free) == 0 {
+		return nil
+	}
+	return &Order{Type: MOVE, Coords: from, Direction: free[rand.Intn(len(free))]}
+}
+
+// spawnOrders makes each hive spawn a bee, as long as the player can afford it
+func spawnOrders(view *GameState, player int) []*Order {
+	var orders []*Order
+	resources := view.PlayerResources[player]
+	cost := view.Rules.BeeCost
+	if view.SpawnCosts != nil {
+		cost = view.SpawnCosts[player]
+	}
+
+	for _, hive := range ownUnits(view, player, HIVE) {
+		if resources < cost {
+			break
+		}
+		for _, dir := range directions {
+			if isFree(view, hive.Coords.Neighbour(dir)) {
+				orders = append(orders, &Order{Type: SPAWN, Coords: hive.Coords, Direction: dir})
+				resources -= cost
+				break
+			}
+		}
+	}
+
+	return orders
+}
+
+func randomBot(view *GameState, player int) []*Order {
+	orders := spawnOrders(view, player)
+
+	for _, bee := range ownUnits(view, player, BEE) {
+		if rand.Intn(3) == 0 {
+			orders = append(orders, &Order{Type: FORAGE, Coords: bee.Coords})
+		} else if move := randomMove(view, bee.Coords); move != nil {
+			orders = append(orders, move)
+		}
+	}
+
+	return orders
+}
+
+func nextToOwnHive(view *GameState, player int, coords Coords) bool {
+	for _, n := range coords.Neighbours() {
+		hex := view.Hexes[n]
+		if hex != nil && hex.Entity != nil && hex.Entity.Type == HIVE && hex.Entity.Player == player {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFlowers(view *GameState, coords Coords) bool {
+	hex := view.Hexes[coords]
+	return hex != nil && hex.Terrain == FIELD && hex.Resources > 0
+}
+
+// greedyBee forages the nearest field, and brings the flower back to the
+// nearest hive
+func greedyBee(view *GameState, player int, bee unit) *Order {
+	var goal func(Coords) bool
+	canForage := false
+
+	if bee.Entity.HasFlower {
+		canForage = nextToOwnHive(view, player, bee.Coords)
+		goal = func(c Coords) bool { return nextToOwnHive(view, player, c) }
+	} else {
+		canForage = hasFlowers(view, bee.Coords)
+		goal = func(c Coords) bool { return hasFlowers(view, c) }
+	}
+
+	if canForage {
+		return &Order{Type: FORAGE, Coords: bee.Coords}
+	}
+	if dir, found := stepTowards(view, bee.Coords, goal); found {
+		return &Order{Type: MOVE, Coords: bee.Coords, Direction: dir}
+	}
+	return randomMove(view, bee.Coords)
+}
+
+// greedyBot only forages, and spends everything on new bees
+func greedyBot(view *GameState, player int) []*Order {
+	orders := spawnOrders(view, player)
+
+	for _, bee := range ownUnits(view, player, BEE) {
+		if order := greedyBee(view, player, bee); order != nil {
+			orders = append(orders, order)
+		}
+	}
+
+	return orders
+}
+
+func isEnemy(view *GameState, player int, coords Coords) bool {
+	hex := view.Hexes[coords]
+	return hex != nil && hex.Entity != nil && hex.Entity.Player != player && hex.Entity.Type != WALL
+}
+
+// rusherBot sends its bees after the nearest enemy units in sight, preferably
+// hives, and attacks them. Bees with no enemy in sight forage.
+func rusherBot(view *GameState, player int) []*Order {
+	orders := spawnOrders(view, player)
+
+	for _, bee := range ownUnits(view, player, BEE) {
+		var targets []Direction
+		for _, dir := range directions {
+			if isEnemy(view, player, bee.Coords.Neighbour(dir)) {
+				targets = append(targets, dir)
+			}
+		}
+
+		if len(targets) > 0 {
+			slices.SortFunc(targets, func(a, b Direction) int {
+				return hiveFirst(view, bee.Coords.Neighbour(a)) - hiveFirst(view, bee.Coords.Neighbour(b))
+			})
+			orders = append(orders, &Order{Type: ATTACK, Coords: bee.Coords, Direction: targets[0]})
+			continue
+		}
+
+		nearEnemy := func(c Coords) bool {
+			return slices.ContainsFunc(c.Neighbours(), func(n Coords) bool { return isEnemy(view, player, n) })
+		}
+
+		if dir, found := stepTowards(view, bee.Coords, nearEnemy); found {
+			orders = append(orders, &Order{Type: MOVE, Coords: bee.Coords, Direction: dir})
+		} else if order := greedyBee(view, player, bee); order != nil {
+			orders = append(orders, order)
+		}
+	}
+
+	return orders
+}
+
+func hiveFirst(view *GameState, coords Coords) int {
+	if view.Hexes[coords].Entity.Type == HIVE {
+		return 0
+	}
+	return 1
+}
+
+// playBots gives the orders of the bots of the session for the new turn. They
+// read the same snapshot as remote agents.
+func (session *GameSession) playBots() {
+	snapshot := session.snapshot.Load()
+
+	for player, bot := range session.bots {
+		if session.State.IsEliminated(player) {
+			continue
+		}
+
+		var view GameState
+		err := json.Unmarshal(snapshot.Views[player], &view)
+		if err != nil {
+			continue
+		}
+
+		err = session.setOrders(player, bot(&view, player))
+		if err != nil {
+			break
+		}
+	}
+}
+
+func botName(level string, player int) string {
+	return fmt.Sprintf("%s-bot-%d", level, player)
+}
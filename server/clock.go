@@ -0,0 +1,173 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	. "hive-arena/common"
+)
+
+// Clock keeps the time of the players, chess clock style: in games with a time
+// bank, each player starts with TimeBank, gets TimeIncrement more at the start
+// of every turn, and spends the time they take to send their orders. Players
+// who run out of time (or hit the turn timeout) lose their turn, and forfeit
+// the game after MaxTimeouts timeouts. The clock stops while the game is paused.
+type Clock struct {
+	// Time of the current turn, not counting pauses
+	turnBegan   time.Time
+	pausedAt    time.Time
+	pausedTotal time.Duration
+
+	limits    []time.Duration
+	latencies []time.Duration
+	answered  []bool
+	timedOut  []bool
+
+	banks    []time.Duration
+	timeouts []int
+}
+
+func NewClock(timing Timing, players int) *Clock {
+	clock := &Clock{
+		banks:    make([]time.Duration, players),
+		timeouts: make([]int, players),
+	}
+	for player := range clock.banks {
+		clock.banks[player] = timing.TimeBank
+	}
+	return clock
+}
+
+// elapsed gives the time spent in the current turn, not counting pauses
+func (clock *Clock) elapsed() time.Duration {
+	now := time.Now()
+	if !clock.pausedAt.IsZero() {
+		now = clock.pausedAt
+	}
+	return now.Sub(clock.turnBegan) - clock.pausedTotal
+}
+
+func (clock *Clock) pause() {
+	clock.pausedAt = time.Now()
+}
+
+func (clock *Clock) resume() {
+	if !clock.pausedAt.IsZero() {
+		clock.pausedTotal += time.Since(clock.pausedAt)
+		clock.pausedAt = time.Time{}
+	}
+}
+
+func (clock *Clock) answer(player int) {
+	clock.answered[player] = true
+	clock.latencies[player] = clock.elapsed()
+}
+
+// startClock sets the time limits of the players for the new turn
+func (session *GameSession) startClock() {
+	clock := session.Clock
+
+	clock.turnBegan = time.Now()
+	clock.pausedTotal = 0
+	clock.pausedAt = time.Time{}
+	if session.Paused {
+		clock.pausedAt = clock.turnBegan
+	}
+
+	clock.limits = make([]time.Duration, session.NumPlayers)
+	clock.latencies = make([]time.Duration, session.NumPlayers)
+	clock.answered = make([]bool, session.NumPlayers)
+	clock.timedOut = make([]bool, session.NumPlayers)
+
+	for player := range session.NumPlayers {
+		clock.limits[player] = session.Timing.TurnTimeout
+		if session.Timing.TimeBank > 0 {
+			clock.banks[player] += session.Timing.TimeIncrement
+			clock.limits[player] = min(clock.limits[player], clock.banks[player])
+		}
+	}
+
+	session.scheduleDeadline()
+}
+
+func (session *GameSession) waitingFor(player int) bool {
+	return session.PendingOrders[player] == nil &&
+		!session.State.IsEliminated(player) &&
+		!session.Clock.timedOut[player]
+}
+
+// scheduleDeadline sets the timer for the next player to run out of time. The
+// clock does not run while the game is paused.
+func (session *GameSession) scheduleDeadline() {
+	session.turnTimeout = nil
+	if session.Paused {
+		return
+	}
+
+	next := time.Duration(-1)
+	for player, limit := range session.Clock.limits {
+		if session.waitingFor(player) && (next < 0 || limit < next) {
+			next = limit
+		}
+	}
+
+	if next >= 0 {
+		session.turnTimeout = time.After(next - session.Clock.elapsed())
+	}
+}
+
+// checkDeadlines marks the players whose time is up, and processes the turn if
+// nobody else is expected
+func (session *GameSession) checkDeadlines() {
+	elapsed := session.Clock.elapsed()
+	for player, limit := range session.Clock.limits {
+		if session.waitingFor(player) && elapsed >= limit {
+			session.Clock.timedOut[player] = true
+			log.Printf("Player %s timed out in game %s", session.Players[player].Name, session.ID)
+		}
+	}
+
+	if session.allPlayed() {
+		session.processTurn()
+	} else {
+		session.scheduleDeadline()
+	}
+}
+
+// stopClock charges the players for the time they took, forfeits the ones
+// who timed out too often, and gives the record of the turn
+func (session *GameSession) stopClock() []PlayerClock {
+	clock := session.Clock
+	records := make([]PlayerClock, session.NumPlayers)
+
+	for player := range session.NumPlayers {
+		if session.State.IsEliminated(player) {
+			continue
+		}
+
+		record := &records[player]
+		latency := clock.latencies[player]
+		if clock.timedOut[player] {
+			latency = clock.limits[player]
+			record.TimedOut = true
+			clock.timeouts[player]++
+		} else if !clock.answered[player] {
+			// The turn was stepped by an admin
+			latency = clock.elapsed()
+		}
+		record.Latency = latency.Milliseconds()
+
+		if session.Timing.TimeBank > 0 {
+			clock.banks[player] = max(0, clock.banks[player]-latency)
+			record.TimeBank = clock.banks[player].Milliseconds()
+		}
+
+		if session.Timing.MaxTimeouts > 0 && clock.timeouts[player] >= session.Timing.MaxTimeouts {
+			session.State.Forfeit(player)
+			record.Forfeited = true
+			log.Printf("Player %s forfeited game %s", session.Players[player].Name, session.ID)
+		}
+	}
+
+	return records
+}
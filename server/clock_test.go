@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	. "hive-arena/common"
+)
+
+// readHistory loads the history file of a finished session
+func readHistory(t *testing.T, session *GameSession) PersistedGame {
+	data, err := os.ReadFile(HistoryDir + "/" + session.historyFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var game PersistedGame
+	err = json.Unmarshal(data, &game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return game
+}
+
+func TestTimeBankForfeit(t *testing.T) {
+	timing := Timing{
+		TurnTimeout:   time.Second,
+		TimeBank:      100 * time.Millisecond,
+		TimeIncrement: 20 * time.Millisecond,
+		MaxTimeouts:   2,
+	}
+	session := testSessionWithTiming(t, 2, 0, timing)
+
+	// Player 1 joins, but never sends orders
+	session.AddPlayer("idle", nil)
+	go playAgent(t, session, "active")
+
+	waitUntilOver(t, session)
+	game := readHistory(t, session)
+
+	// The history starts with the initial state
+	if len(game.History) != 3 {
+		t.Fatalf("game lasted %d turns, expected 2", len(game.History)-1)
+	}
+
+	// The idle player spends their bank and increment on the first turn, then
+	// only has the increment
+	first, second := game.History[1].Clock, game.History[2].Clock
+	if !first[0].TimedOut || first[0].Forfeited || first[0].TimeBank != 0 || first[0].Latency < 120 {
+		t.Errorf("first turn of the idle player: %+v", first[0])
+	}
+	if !second[0].TimedOut || !second[0].Forfeited || second[0].Latency < 20 || second[0].Latency >= 120 {
+		t.Errorf("second turn of the idle player: %+v", second[0])
+	}
+
+	// The active player answers quickly, and keeps most of their bank
+	for _, record := range []PlayerClock{first[1], second[1]} {
+		if record.TimedOut || record.Forfeited || record.TimeBank <= 0 || record.TimeBank > 140 || record.Latency > 100 {
+			t.Errorf("active player: %+v", record)
+		}
+	}
+
+	state := game.History[2].State
+	if !state.GameOver || state.EndReason != ELIMINATION || len(state.Winners) != 1 || state.Winners[0] != 1 {
+		t.Errorf("game ended with %s, winners %v", state.EndReason, state.Winners)
+	}
+}
+
+func TestTimeoutsWithoutForfeit(t *testing.T) {
+	timing := Timing{TurnTimeout: 20 * time.Millisecond}
+	session := testSessionWithTiming(t, 2, 3, timing)
+
+	session.AddPlayer("idle", nil)
+	session.AddPlayer("idle too", nil)
+
+	waitUntilOver(t, session)
+	game := readHistory(t, session)
+
+	if len(game.History) != 4 {
+		t.Fatalf("game lasted %d turns, expected 3", len(game.History)-1)
+	}
+	for _, turn := range game.History[1:] {
+		for _, record := range turn.Clock {
+			if !record.TimedOut || record.Forfeited || record.TimeBank != 0 || record.Latency != 20 {
+				t.Errorf("turn %d: %+v", turn.State.Turn, record)
+			}
+		}
+	}
+}
@@ -8,7 +8,7 @@ import (
 	"math/rand"
 	"os"
 	"slices"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -18,28 +18,108 @@ import (
 
 const MinTurnDuration = 500 * time.Millisecond
 const TurnTimeout = 2 * time.Second
+const SocketWriteTimeout = time.Second
+
+// Limits of the timing games can ask for
+const MinTurnTimeoutLimit = 10 * time.Millisecond
+const MaxTurnTimeoutLimit = time.Minute
+const MaxMinTurnDurationLimit = 10 * time.Second
+const MaxTurnsLimit = 10000
+const MaxTimeBankLimit = time.Hour
+
+type Timing struct {
+	// How long players have to send their orders
+	TurnTimeout time.Duration
+
+	// How long to wait between the end of a turn and the start of the next
+	MinTurnDuration time.Duration
+
+	// Time bank of each player (0 for none), and the time added to it every
+	// turn (see Clock)
+	TimeBank      time.Duration
+	TimeIncrement time.Duration
+
+	// Number of timeouts after which a player forfeits (0 for never)
+	MaxTimeouts int
+}
+
+func DefaultTiming() Timing {
+	timing := Timing{TurnTimeout: TurnTimeout, MinTurnDuration: MinTurnDuration}
+	if DevMode {
+		timing.MinTurnDuration = 0
+	}
+	return timing
+}
 
 type Player struct {
 	ID    int
 	Name  string
 	Token string
+
+	// The registered team playing, if any
+	Team *Team
 }
 
+// A GameSession is driven by a single goroutine, its event loop (see run),
+// which owns the game state, the players and the sockets. Other goroutines
+// only talk to it through channels, or read the fields that never change after
+// creation and the published snapshot.
 type GameSession struct {
-	mutex sync.Mutex
-
 	ID           string
 	Map          string
 	CreatedDate  time.Time
 	AdminToken   string
 	PlayerTokens []string
-	Players      []Player
-	State        *GameState
+	NumPlayers   int
+	Timing       Timing
+
+	// Serialized states of the current turn, replaced when a turn begins
+	snapshot atomic.Pointer[Snapshot]
+
+	// Owned by the event loop
+
+	Players []Player
+	State   *GameState
 
 	PendingOrders [][]*Order
 	History       []Turn
+	Clock         *Clock
 
 	Sockets []*websocket.Conn
+
+	// Players played by the server itself
+	bots map[int]Bot
+
+	// A paused game waits for an admin to step or resume it
+	Paused bool
+
+	// Set between the end of a turn and the start of the next
+	betweenTurns bool
+
+	turnTimeout <-chan time.Time
+	turnStart   <-chan time.Time
+
+	// Messages to the event loop
+
+	joins    chan joinMessage
+	orders   chan ordersMessage
+	sockets  chan *websocket.Conn
+	statuses chan chan SessionStatus
+	admin    chan adminMessage
+	done     chan struct{}
+}
+
+type joinMessage struct {
+	name  string
+	team  *Team
+	bot   Bot
+	reply chan *Player
+}
+
+type ordersMessage struct {
+	player int
+	orders []*Order
+	reply  chan error
 }
 
 func generateTokens(count int) []string {
@@ -52,110 +132,292 @@ func generateTokens(count int) []string {
 	return slices.Collect(maps.Keys(tokens))
 }
 
-func NewGameSession(id string, players int, mapname string, mapdata MapData) *GameSession {
+func NewGameSession(id string, players int, mapname string, mapdata MapData, timing Timing) *GameSession {
 
 	tokens := generateTokens(players + 1)
 	state := NewGameState(mapdata, players)
 
-	return &GameSession{
+	session := &GameSession{
 		ID:           id,
 		Map:          mapname,
 		CreatedDate:  time.Now(),
 		AdminToken:   tokens[0],
 		PlayerTokens: tokens[1:],
+		NumPlayers:   players,
+		Timing:       timing,
 		State:        state,
 		History:      []Turn{{Orders: nil, State: state.Clone()}},
+		Clock:        NewClock(timing, players),
+		bots:         make(map[int]Bot),
+
+		joins:    make(chan joinMessage),
+		orders:   make(chan ordersMessage),
+		sockets:  make(chan *websocket.Conn),
+		statuses: make(chan chan SessionStatus),
+		admin:    make(chan adminMessage),
+		done:     make(chan struct{}),
+	}
+
+	go session.run()
+
+	return session
+}
+
+func (session *GameSession) run() {
+	for {
+		// Orders wait for the next turn to begin, unless the game is paused
+		// and it will not begin soon
+		orders := session.orders
+		if session.betweenTurns && !session.Paused {
+			orders = nil
+		}
+
+		select {
+		case message := <-session.joins:
+			message.reply <- session.addPlayer(message.name, message.team, message.bot)
+
+		case message := <-orders:
+			message.reply <- session.setOrders(message.player, message.orders)
+
+		case socket := <-session.sockets:
+			session.registerWebSocket(socket)
+
+		case reply := <-session.statuses:
+			reply <- session.status()
+
+		case message := <-session.admin:
+			message.reply <- session.applyAdminAction(message.action, message.player)
+
+		case <-session.turnTimeout:
+			session.checkDeadlines()
+
+		case <-session.turnStart:
+			session.beginTurn()
+
+		case <-session.done:
+			for _, socket := range session.Sockets {
+				socket.Close()
+			}
+			return
+		}
 	}
 }
 
-func (session *GameSession) IsFull() bool {
-	return len(session.Players) == session.State.NumPlayers
+// Close stops the event loop of a session that is no longer needed
+func (session *GameSession) Close() {
+	close(session.done)
 }
 
-func (session *GameSession) AddPlayer(name string) *Player {
-	session.mutex.Lock()
-	defer session.mutex.Unlock()
+// send passes a message to the event loop, and tells whether it was received
+func send[T any](session *GameSession, channel chan T, message T) bool {
+	select {
+	case channel <- message:
+		return true
+	case <-session.done:
+		return false
+	}
+}
 
-	if session.IsFull() {
+// AddPlayer adds a player to the session, playing for the given team if it is
+// not nil
+func (session *GameSession) AddPlayer(name string, team *Team) *Player {
+	reply := make(chan *Player, 1)
+	if !send(session, session.joins, joinMessage{name, team, nil, reply}) {
 		return nil
 	}
+	return <-reply
+}
 
-	id := len(session.Players)
-	player := Player{id, name, session.PlayerTokens[id]}
+// AddBot fills a player slot with a built-in bot of the given level
+func (session *GameSession) AddBot(level string) (*Player, error) {
+	bot, ok := Bots[level]
+	if !ok {
+		return nil, fmt.Errorf("Invalid bot level: %s", level)
+	}
 
-	session.Players = append(session.Players, player)
+	reply := make(chan *Player, 1)
+	if !send(session, session.joins, joinMessage{level, nil, bot, reply}) {
+		return nil, fmt.Errorf("Game is over")
+	}
 
-	if session.IsFull() {
-		session.BeginTurn()
+	player := <-reply
+	if player == nil {
+		return nil, fmt.Errorf("Game is full")
 	}
+	return player, nil
+}
 
-	return &player
+func (session *GameSession) SetOrders(playerid int, orders []*Order) error {
+	reply := make(chan error, 1)
+	if !send(session, session.orders, ordersMessage{playerid, orders, reply}) {
+		return fmt.Errorf("Game is over")
+	}
+	return <-reply
 }
 
-func (session *GameSession) Player(token string) *Player {
-	session.mutex.Lock()
-	defer session.mutex.Unlock()
+func (session *GameSession) RegisterWebSocket(socket *websocket.Conn) {
+	if !send(session, session.sockets, socket) {
+		socket.Close()
+	}
+}
 
-	playerid := slices.Index(session.PlayerTokens, token)
-	if playerid < 0 {
-		return nil
+func (session *GameSession) Status() SessionStatus {
+	reply := make(chan SessionStatus, 1)
+	if !send(session, session.statuses, reply) {
+		return SessionStatus{Id: session.ID}
 	}
-	return &session.Players[playerid]
+	return <-reply
 }
 
-func (session *GameSession) GetView(token string) *GameState {
-	session.mutex.Lock()
-	defer session.mutex.Unlock()
+// PlayerID gives the player using the given token, or -1 for an invalid token
+func (session *GameSession) PlayerID(token string) int {
+	return slices.Index(session.PlayerTokens, token)
+}
+
+// Snapshot holds the JSON encoded full state and player views of a turn. It is
+// never modified once published, so it can be read without locking.
+type Snapshot struct {
+	Turn     uint
+	GameOver bool
+	ETag     string
+
+	// Counts the snapshots of the session, as a turn can be published again,
+	// for instance when a game is aborted
+	Version uint
+	Admin   []byte
+	Views   [][]byte
+}
+
+func encodeState(state *GameState) []byte {
+	data, _ := json.Marshal(state)
+	return append(data, '\n')
+}
+
+func (session *GameSession) publishSnapshot() {
+	state := session.State
+
+	version := uint(0)
+	if previous := session.snapshot.Load(); previous != nil {
+		version = previous.Version + 1
+	}
 
-	playerid := slices.Index(session.PlayerTokens, token)
+	snapshot := &Snapshot{
+		Turn:     state.Turn,
+		GameOver: state.GameOver,
+		ETag:     fmt.Sprintf(`"%s-%d-%d"`, session.ID, state.Turn, version),
+		Version:  version,
+		Admin:    encodeState(state),
+	}
+
+	for player := range state.NumPlayers {
+		snapshot.Views = append(snapshot.Views, encodeState(state.PlayerView(player)))
+	}
+
+	session.snapshot.Store(snapshot)
+}
+
+// GetSnapshot gives the state of the current turn for the given token, or nil
+// for an invalid token or a game that has not started
+func (session *GameSession) GetSnapshot(token string) (data []byte, etag string) {
+	snapshot := session.snapshot.Load()
+	if snapshot == nil {
+		return nil, ""
+	}
+
+	if token == session.AdminToken {
+		return snapshot.Admin, snapshot.ETag
+	}
+
+	playerid := session.PlayerID(token)
 	if playerid < 0 {
-		return nil
+		return nil, ""
 	}
 
-	return session.State.PlayerView(playerid)
+	return snapshot.Views[playerid], snapshot.ETag
+}
+
+func (session *GameSession) HasStarted() bool {
+	return session.snapshot.Load() != nil
+}
+
+func (session *GameSession) IsOver() bool {
+	snapshot := session.snapshot.Load()
+	return snapshot != nil && snapshot.GameOver
+}
+
+// The methods below are only called from the event loop
+
+func (session *GameSession) isFull() bool {
+	return len(session.Players) == session.NumPlayers
 }
 
-func (session *GameSession) BeginTurn() {
+func (session *GameSession) addPlayer(name string, team *Team, bot Bot) *Player {
+	if session.isFull() {
+		return nil
+	}
+
+	id := len(session.Players)
+	if bot != nil {
+		name = botName(name, id)
+		session.bots[id] = bot
+	}
+	player := Player{id, name, session.PlayerTokens[id], team}
+
+	session.Players = append(session.Players, player)
 
-	if !DevMode {
-		time.Sleep(MinTurnDuration)
+	if session.isFull() {
+		session.beginTurn()
 	}
 
+	return &player
+}
+
+func (session *GameSession) beginTurn() {
+	session.turnStart = nil
+	session.betweenTurns = false
+
+	session.publishSnapshot()
 	session.notifySockets()
 
 	if session.State.GameOver {
+		session.turnTimeout = nil
 		return
 	}
 
 	session.PendingOrders = make([][]*Order, session.State.NumPlayers)
-
-	currentTurn := session.State.Turn
-	time.AfterFunc(TurnTimeout, func() {
-		session.mutex.Lock()
-		defer session.mutex.Unlock()
-
-		if session.State.Turn == currentTurn {
-			session.processTurn()
-		}
-	})
+	session.startClock()
+	session.playBots()
 }
 
-func (session *GameSession) SetOrders(playerid int, orders []*Order) {
-	session.mutex.Lock()
-	defer session.mutex.Unlock()
+func (session *GameSession) setOrders(playerid int, orders []*Order) error {
+	if session.State.GameOver {
+		return fmt.Errorf("Game is over")
+	}
+	if session.State.IsEliminated(playerid) {
+		return fmt.Errorf("Player has been eliminated")
+	}
+	if session.betweenTurns {
+		return fmt.Errorf("Game is paused, and the next turn has not begun")
+	}
+	if session.Clock.timedOut[playerid] {
+		return fmt.Errorf("Time is up for this turn")
+	}
 
 	session.PendingOrders[playerid] = orders
+	session.Clock.answer(playerid)
 
 	log.Printf("Player %s posted orders in game %s", session.Players[playerid].Name, session.ID)
 
-	if session.allPlayed() {
+	if session.allPlayed() && !session.Paused {
 		session.processTurn()
 	}
+
+	return nil
 }
 
 func (session *GameSession) allPlayed() bool {
-	for _, orders := range session.PendingOrders {
-		if orders == nil {
+	for player := range session.PendingOrders {
+		if session.waitingFor(player) {
 			return false
 		}
 	}
@@ -165,55 +427,65 @@ func (session *GameSession) allPlayed() bool {
 func (session *GameSession) processTurn() {
 	log.Printf("Processing orders for game %s, turn %d", session.ID, session.State.Turn)
 
+	clock := session.stopClock()
 	results, _ := session.State.ProcessOrders(session.PendingOrders)
-	session.History = append(session.History, Turn{Orders: results, State: session.State.Clone()})
+	session.History = append(session.History, Turn{Orders: results, State: session.State.Clone(), Clock: clock})
 
 	if session.State.GameOver {
 		log.Printf("Game %s is over", session.ID)
 		session.persist()
 	}
 
-	session.BeginTurn()
+	// The next turn begins after a short while, instead of right away
+	session.turnTimeout = nil
+	session.betweenTurns = true
+	if !session.Paused {
+		session.turnStart = time.After(session.Timing.MinTurnDuration)
+	}
 }
 
-func (session *GameSession) RegisterWebSocket(socket *websocket.Conn) {
-	session.mutex.Lock()
-	defer session.mutex.Unlock()
+func (session *GameSession) registerWebSocket(socket *websocket.Conn) {
+	if session.isFull() && !session.notifySocket(socket) {
+		return
+	}
 
 	session.Sockets = append(session.Sockets, socket)
-
-	if session.IsFull() {
-		session.notifySocket(socket)
-	}
 }
 
-func (session *GameSession) notifySocket(socket *websocket.Conn) {
+// notifySocket tells a listener about the current turn, and tells whether the
+// socket is still open. Slow listeners are dropped rather than holding up the
+// game.
+func (session *GameSession) notifySocket(socket *websocket.Conn) bool {
 	message, _ := json.Marshal(map[string]any{
 		"turn":     session.State.Turn,
 		"gameOver": session.State.GameOver,
 	})
 
-	socket.WriteMessage(websocket.TextMessage, message)
+	socket.SetWriteDeadline(time.Now().Add(SocketWriteTimeout))
+	err := socket.WriteMessage(websocket.TextMessage, message)
 
-	if session.State.GameOver {
+	if err != nil || session.State.GameOver {
 		socket.Close()
+		return false
 	}
+	return true
 }
 
 func (session *GameSession) notifySockets() {
-	for _, socket := range session.Sockets {
-		session.notifySocket(socket)
-	}
+	session.Sockets = slices.DeleteFunc(session.Sockets, func(socket *websocket.Conn) bool {
+		return !session.notifySocket(socket)
+	})
 }
 
-func (session *GameSession) persist() {
+// historyFile gives the name of the file the game is saved to, in the history
+// directory
+func (session *GameSession) historyFile() string {
 	date, _ := session.CreatedDate.MarshalText()
-	path := fmt.Sprintf("%s/%s-%s-%s.json",
-		HistoryDir,
-		date,
-		session.ID,
-		session.Map,
-	)
+	return fmt.Sprintf("%s-%s-%s.json", date, session.ID, session.Map)
+}
+
+func (session *GameSession) persist() {
+	path := HistoryDir + "/" + session.historyFile()
 
 	players := make([]string, len(session.Players))
 	for i, player := range session.Players {
@@ -232,9 +504,26 @@ func (session *GameSession) persist() {
 	defer file.Close()
 
 	json.NewEncoder(file).Encode(info)
+
+	end := gameEnd{session.State.Turn, session.State.Winners, session.State.EndReason}
+	game := indexedGame{
+		Id:          session.ID,
+		Map:         session.Map,
+		CreatedDate: session.CreatedDate,
+		Players:     players,
+		History:     []indexedTurn{{end}},
+	}
+	PastGames.Add(game.summarize(session.historyFile()))
+
+	if RegisteredTeams != nil {
+		err := RegisteredTeams.RecordGame(session)
+		if err != nil {
+			log.Printf("Could not record game %s for the teams: %s", session.ID, err)
+		}
+	}
 }
 
-func (session *GameSession) Status() SessionStatus {
+func (session *GameSession) status() SessionStatus {
 
 	var players []string
 	for _, player := range session.Players {
@@ -248,5 +537,13 @@ func (session *GameSession) Status() SessionStatus {
 		NumPlayers:  session.State.NumPlayers,
 		Players:     players,
 		GameOver:    session.State.GameOver,
+		Paused:      session.Paused,
+
+		TurnTimeout:     session.Timing.TurnTimeout.Milliseconds(),
+		MinTurnDuration: session.Timing.MinTurnDuration.Milliseconds(),
+		MaxTurns:        session.State.Rules.TurnLimit,
+		TimeBank:        session.Timing.TimeBank.Milliseconds(),
+		TimeIncrement:   session.Timing.TimeIncrement.Milliseconds(),
+		MaxTimeouts:     session.Timing.MaxTimeouts,
 	}
 }
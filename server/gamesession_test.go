@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	. "hive-arena/common"
+)
+
+// testSession creates a session in a temporary directory, so that finished
+// games are saved there
+func testSession(t *testing.T, players int, turnLimit uint) *GameSession {
+	return testSessionWithTiming(t, players, turnLimit, Timing{TurnTimeout: 200 * time.Millisecond})
+}
+
+func testSessionWithTiming(t *testing.T, players int, turnLimit uint, timing Timing) *GameSession {
+	mapData, err := LoadMap("../maps/balanced.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Chdir(t.TempDir())
+	err = os.Mkdir(HistoryDir, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	PastGames = &HistoryIndex{}
+
+	mapData.Rules.TurnLimit = turnLimit
+
+	session := NewGameSession("test", players, "balanced", mapData, timing)
+	t.Cleanup(session.Close)
+	return session
+}
+
+func waitUntilOver(t *testing.T, session *GameSession) {
+	deadline := time.Now().Add(30 * time.Second)
+	for !session.IsOver() {
+		if time.Now().After(deadline) {
+			t.Fatal("game did not end")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// playAgent joins a session and plays random orders every turn until the game
+// is over, as a remote agent would
+func playAgent(t *testing.T, session *GameSession, name string) {
+	player := session.AddPlayer(name, nil)
+	if player == nil {
+		t.Errorf("%s could not join", name)
+		return
+	}
+
+	lastTurn := ""
+	for !session.IsOver() {
+		data, etag := session.GetSnapshot(player.Token)
+		if data == nil || etag == lastTurn {
+			time.Sleep(time.Millisecond)
+			continue
+		}
+		lastTurn = etag
+
+		var view GameState
+		err := json.Unmarshal(data, &view)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+
+		// Orders can arrive late, after a timeout or once the game is over
+		session.SetOrders(player.ID, randomBot(&view, player.ID))
+	}
+}
+
+func TestConcurrentSession(t *testing.T) {
+	session := testSession(t, 4, 40)
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := range session.NumPlayers {
+		wg.Go(func() { playAgent(t, session, fmt.Sprintf("agent-%d", i)) })
+	}
+
+	// Readers hammering the session while it plays
+	for range 4 {
+		wg.Go(func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				status := session.Status()
+				if status.Id != session.ID {
+					t.Errorf("invalid status: %v", status)
+				}
+
+				data, _ := session.GetSnapshot(session.AdminToken)
+				if data != nil {
+					var state GameState
+					err := json.Unmarshal(data, &state)
+					if err != nil {
+						t.Error(err)
+					}
+				}
+			}
+		})
+	}
+
+	waitUntilOver(t, session)
+	close(done)
+	wg.Wait()
+
+	status := session.Status()
+	if !status.GameOver || len(status.Players) != session.NumPlayers {
+		t.Errorf("unexpected final status: %+v", status)
+	}
+
+	var state GameState
+	data, _ := session.GetSnapshot(session.AdminToken)
+	json.Unmarshal(data, &state)
+	if state.Turn == 0 || state.Turn > 40 {
+		t.Errorf("game ended at turn %d", state.Turn)
+	}
+}
+
+func TestJoinRace(t *testing.T) {
+	session := testSession(t, 3, 5)
+
+	var wg sync.WaitGroup
+	joined := make(chan *Player, 10)
+	for i := range 10 {
+		wg.Go(func() { joined <- session.AddPlayer(fmt.Sprintf("agent-%d", i), nil) })
+	}
+	wg.Wait()
+	close(joined)
+
+	seats := map[int]bool{}
+	for player := range joined {
+		if player == nil {
+			continue
+		}
+		if seats[player.ID] {
+			t.Errorf("seat %d given twice", player.ID)
+		}
+		seats[player.ID] = true
+	}
+
+	if len(seats) != 3 || !session.HasStarted() {
+		t.Errorf("%d players joined, expected 3", len(seats))
+	}
+}
+
+func TestAdminDuringPlay(t *testing.T) {
+	session := testSession(t, 2, 30)
+
+	var wg sync.WaitGroup
+	for i := range session.NumPlayers {
+		wg.Go(func() { playAgent(t, session, fmt.Sprintf("agent-%d", i)) })
+	}
+
+	for !session.HasStarted() {
+		time.Sleep(time.Millisecond)
+	}
+
+	for range 5 {
+		session.Admin(PAUSE, -1)
+		session.Admin(STEP, -1)
+		session.Admin(RESUME, -1)
+	}
+
+	waitUntilOver(t, session)
+	wg.Wait()
+}
+
+func TestKickEndsGame(t *testing.T) {
+	session := testSession(t, 3, 0)
+	for _, name := range []string{"a", "b", "c"} {
+		session.AddPlayer(name, nil)
+	}
+
+	// Nobody plays: kicking down to a single player must end the game by itself
+	for _, player := range []int{2, 0} {
+		err := session.Admin(KICK, player)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if !session.IsOver() {
+		t.Fatal("game not over after kicking all players but one")
+	}
+
+	game := readHistory(t, session)
+	state := game.History[len(game.History)-1].State
+	if state.EndReason != ELIMINATION || !slices.Equal(state.Winners, []int{1}) {
+		t.Errorf("game ended with %s, winners %v", state.EndReason, state.Winners)
+	}
+}
+
+func TestBotsOnlySession(t *testing.T) {
+	session := testSession(t, 2, 20)
+
+	for _, level := range []string{"greedy", "rusher"} {
+		_, err := session.AddBot(level)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitUntilOver(t, session)
+}
+
+func TestAbortChangesETag(t *testing.T) {
+	session := testSession(t, 2, 0)
+	session.AddPlayer("a", nil)
+	session.AddPlayer("b", nil)
+
+	_, before := session.GetSnapshot(session.AdminToken)
+
+	err := session.Admin(ABORT, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, after := session.GetSnapshot(session.AdminToken)
+	if after == before {
+		t.Errorf("ETag %s did not change after abort", after)
+	}
+
+	var state GameState
+	json.Unmarshal(data, &state)
+	if !state.GameOver || state.EndReason != ABORTED {
+		t.Errorf("aborted game not published: %v %s", state.GameOver, state.EndReason)
+	}
+}
+
+func TestOrdersWhilePausedBetweenTurns(t *testing.T) {
+	timing := Timing{TurnTimeout: time.Second, MinTurnDuration: time.Minute}
+	session := testSessionWithTiming(t, 2, 0, timing)
+	a := session.AddPlayer("a", nil)
+	b := session.AddPlayer("b", nil)
+
+	session.SetOrders(a.ID, []*Order{})
+	session.SetOrders(b.ID, []*Order{})
+
+	err := session.Admin(PAUSE, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- session.SetOrders(a.ID, []*Order{}) }()
+
+	select {
+	case err := <-result:
+		if err == nil {
+			t.Error("orders accepted between turns")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("orders blocked while the game is paused")
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	. "hive-arena/common"
+)
+
+const DefaultSearchLimit = 50
+const MaxSearchLimit = 500
+
+// The index of the games saved in the history directory
+var PastGames *HistoryIndex
+
+// HistoryIndex keeps a summary of each past game, newest first, so that they
+// can be searched without reading the history files
+type HistoryIndex struct {
+	mutex sync.Mutex
+	games []GameSummary
+}
+
+// indexedGame holds the parts of a history file needed by the index, so that
+// the states of the game do not have to be decoded
+type indexedGame struct {
+	Id          string        `json:"id"`
+	Map         string        `json:"map"`
+	CreatedDate time.Time     `json:"createdDate"`
+	Players     []string      `json:"players"`
+	History     []indexedTurn `json:"history"`
+}
+
+type indexedTurn struct {
+	State gameEnd `json:"state"`
+}
+
+type gameEnd struct {
+	Turn      uint      `json:"turn"`
+	Winners   []int     `json:"winners"`
+	EndReason EndReason `json:"endReason"`
+}
+
+func (game *indexedGame) summarize(file string) GameSummary {
+	summary := GameSummary{
+		Id:          game.Id,
+		Map:         game.Map,
+		CreatedDate: game.CreatedDate,
+		Players:     game.Players,
+		Winners:     []string{},
+		File:        file,
+	}
+
+	if len(game.History) > 0 {
+		end := game.History[len(game.History)-1].State
+		summary.EndReason = end.EndReason
+		summary.Turns = end.Turn
+		for _, winner := range end.Winners {
+			if winner < len(game.Players) {
+				summary.Winners = append(summary.Winners, game.Players[winner])
+			}
+		}
+	}
+
+	return summary
+}
+
+// LoadHistoryIndex reads all the games of the history directory. Files that
+// cannot be read are skipped.
+func LoadHistoryIndex(dir string) *HistoryIndex {
+	index := &HistoryIndex{}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("Could not read history directory: %s", err)
+		return index
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".json" {
+			continue
+		}
+
+		file, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			log.Printf("Could not read history file %s: %s", name, err)
+			continue
+		}
+
+		var game indexedGame
+		err = json.NewDecoder(file).Decode(&game)
+		file.Close()
+		if err != nil {
+			log.Printf("Could not read history file %s: %s", name, err)
+			continue
+		}
+
+		index.games = append(index.games, game.summarize(name))
+	}
+
+	slices.SortFunc(index.games, func(a, b GameSummary) int {
+		return b.CreatedDate.Compare(a.CreatedDate)
+	})
+
+	log.Printf("Indexed %d past games", len(index.games))
+
+	return index
+}
+
+// Add indexes a game that was just saved, replacing any previous version
+func (index *HistoryIndex) Add(summary GameSummary) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.games = slices.DeleteFunc(index.games, func(g GameSummary) bool {
+		return g.Id == summary.Id
+	})
+
+	i, _ := slices.BinarySearchFunc(index.games, summary, func(g GameSummary, s GameSummary) int {
+		return s.CreatedDate.Compare(g.CreatedDate)
+	})
+	index.games = slices.Insert(index.games, i, summary)
+}
+
+type HistoryFilter struct {
+	Player   string
+	Map      string
+	Winner   string
+	From     time.Time
+	To       time.Time
+	MinTurns uint
+	MaxTurns uint
+}
+
+func (filter HistoryFilter) matches(game GameSummary) bool {
+	return (filter.Player == "" || slices.Contains(game.Players, filter.Player)) &&
+		(filter.Map == "" || game.Map == filter.Map) &&
+		(filter.Winner == "" || slices.Contains(game.Winners, filter.Winner)) &&
+		(filter.From.IsZero() || !game.CreatedDate.Before(filter.From)) &&
+		(filter.To.IsZero() || game.CreatedDate.Before(filter.To)) &&
+		game.Turns >= filter.MinTurns &&
+		(filter.MaxTurns == 0 || game.Turns <= filter.MaxTurns)
+}
+
+// Search gives a page of the games matching the filter, newest first, and the
+// total number of matching games
+func (index *HistoryIndex) Search(filter HistoryFilter, offset int, limit int) ([]GameSummary, int) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	games := []GameSummary{}
+	total := 0
+	for _, game := range index.games {
+		if !filter.matches(game) {
+			continue
+		}
+		if total >= offset && len(games) < limit {
+			games = append(games, game)
+		}
+		total++
+	}
+
+	return games, total
+}
+
+func parseDate(text string) (time.Time, error) {
+	date, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		date, err = time.Parse(time.DateOnly, text)
+	}
+	return date, err
+}
+
+// parseHistoryQuery reads the filter and the page of a history search
+func parseHistoryQuery(query url.Values) (HistoryFilter, int, int, error) {
+	filter := HistoryFilter{
+		Player: query.Get("player"),
+		Map:    query.Get("map"),
+		Winner: query.Get("winner"),
+	}
+
+	for name, date := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if text := query.Get(name); text != "" {
+			var err error
+			*date, err = parseDate(text)
+			if err != nil {
+				return filter, 0, 0, fmt.Errorf("invalid date for %s: %s", name, text)
+			}
+		}
+	}
+
+	for name, turns := range map[string]*uint{"minTurns": &filter.MinTurns, "maxTurns": &filter.MaxTurns} {
+		if text := query.Get(name); text != "" {
+			n, err := strconv.ParseUint(text, 10, 32)
+			if err != nil {
+				return filter, 0, 0, fmt.Errorf("invalid value for %s: %s", name, text)
+			}
+			*turns = uint(n)
+		}
+	}
+
+	offset := 0
+	if text := query.Get("offset"); text != "" {
+		n, err := strconv.Atoi(text)
+		if err != nil || n < 0 {
+			return filter, 0, 0, fmt.Errorf("invalid value for offset: %s", text)
+		}
+		offset = n
+	}
+
+	limit := DefaultSearchLimit
+	if text := query.Get("limit"); text != "" {
+		n, err := strconv.Atoi(text)
+		if err != nil || n < 1 || n > MaxSearchLimit {
+			return filter, 0, 0, fmt.Errorf("limit should be between 1 and %d", MaxSearchLimit)
+		}
+		limit = n
+	}
+
+	return filter, offset, limit, nil
+}
+
+func (server *Server) handleHistorySearch(w http.ResponseWriter, r *http.Request) {
+	logRoute(r)
+
+	filter, offset, limit, err := parseHistoryQuery(r.URL.Query())
+	if err != nil {
+		writeJson(w, "Invalid search: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	games, total := PastGames.Search(filter, offset, limit)
+
+	writeJson(w, HistorySearchResponse{
+		Total:  total,
+		Offset: offset,
+		Limit:  limit,
+		Games:  games,
+	}, http.StatusOK)
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	. "hive-arena/common"
+)
+
+// writeHistory saves a finished game in the history directory, as persist does
+func writeHistory(t *testing.T, dir string, id string, mapname string, date string, players []string, winners []int, turns uint) {
+	created, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := &GameState{Turn: turns, Winners: winners, GameOver: true, EndReason: TURN_LIMIT_REACHED}
+	game := PersistedGame{
+		Id:          id,
+		Map:         mapname,
+		CreatedDate: created,
+		Players:     players,
+		History:     []Turn{{State: &GameState{}}, {State: state}},
+	}
+
+	data, err := json.Marshal(game)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, id+".json"), data, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testIndex(t *testing.T) *HistoryIndex {
+	dir := t.TempDir()
+	writeHistory(t, dir, "a", "balanced", "2026-01-01T10:00:00Z", []string{"ann", "bob"}, []int{0}, 100)
+	writeHistory(t, dir, "b", "tiny", "2026-01-02T10:00:00Z", []string{"bob", "cid"}, []int{1}, 50)
+	writeHistory(t, dir, "c", "balanced", "2026-01-03T10:00:00Z", []string{"ann", "cid"}, []int{0, 1}, 200)
+	writeHistory(t, dir, "d", "balanced", "2026-01-04T00:00:00Z", []string{"bob", "ann"}, []int{1}, 10)
+
+	// Files that are not games are skipped
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello"), 0644)
+
+	return LoadHistoryIndex(dir)
+}
+
+func ids(games []GameSummary) []string {
+	var result []string
+	for _, game := range games {
+		result = append(result, game.Id)
+	}
+	return result
+}
+
+func TestHistorySearch(t *testing.T) {
+	index := testIndex(t)
+
+	tests := []struct {
+		query string
+		games []string
+	}{
+		{"", []string{"d", "c", "b", "a"}},
+		{"player=ann", []string{"d", "c", "a"}},
+		{"map=balanced&player=bob", []string{"d", "a"}},
+		{"winner=cid", []string{"c", "b"}},
+		{"winner=ann", []string{"d", "c", "a"}},
+		{"winner=bob", nil},
+		{"from=2026-01-02", []string{"d", "c", "b"}},
+		{"to=2026-01-04", []string{"c", "b", "a"}},
+		{"to=2026-01-03T10:00:00Z", []string{"b", "a"}},
+		{"from=2026-01-02T10:00:00Z&to=2026-01-03T10:00:01Z", []string{"c", "b"}},
+		{"minTurns=50&maxTurns=100", []string{"b", "a"}},
+		{"player=nobody", nil},
+	}
+
+	for _, test := range tests {
+		query, _ := url.ParseQuery(test.query)
+		filter, offset, limit, err := parseHistoryQuery(query)
+		if err != nil {
+			t.Errorf("%s: %s", test.query, err)
+			continue
+		}
+
+		games, total := index.Search(filter, offset, limit)
+		if !slices.Equal(ids(games), test.games) || total != len(test.games) {
+			t.Errorf("%s: games %v (total %d), expected %v", test.query, ids(games), total, test.games)
+		}
+	}
+}
+
+func TestHistorySummary(t *testing.T) {
+	index := testIndex(t)
+
+	games, _ := index.Search(HistoryFilter{Map: "tiny"}, 0, 10)
+	if len(games) != 1 {
+		t.Fatalf("%d games on tiny", len(games))
+	}
+
+	game := games[0]
+	if game.File != "b.json" || game.Turns != 50 || game.EndReason != TURN_LIMIT_REACHED ||
+		!slices.Equal(game.Players, []string{"bob", "cid"}) || !slices.Equal(game.Winners, []string{"cid"}) {
+		t.Errorf("unexpected summary: %+v", game)
+	}
+}
+
+func TestHistoryPagination(t *testing.T) {
+	index := testIndex(t)
+
+	tests := []struct {
+		offset, limit int
+		games         []string
+	}{
+		{0, 2, []string{"d", "c"}},
+		{2, 2, []string{"b", "a"}},
+		{3, 5, []string{"a"}},
+		{4, 5, []string{}},
+	}
+
+	for _, test := range tests {
+		games, total := index.Search(HistoryFilter{}, test.offset, test.limit)
+		if !slices.Equal(ids(games), test.games) || total != 4 {
+			t.Errorf("offset %d, limit %d: games %v (total %d), expected %v", test.offset, test.limit, ids(games), total, test.games)
+		}
+	}
+
+	games, total := index.Search(HistoryFilter{Player: "ann"}, 1, 1)
+	if !slices.Equal(ids(games), []string{"c"}) || total != 3 {
+		t.Errorf("second page of ann's games: %v (total %d)", ids(games), total)
+	}
+}
+
+func TestHistoryQueryErrors(t *testing.T) {
+	for _, text := range []string{
+		"from=yesterday",
+		"to=2026-13-01",
+		"minTurns=-1",
+		"maxTurns=many",
+		"offset=-1",
+		"limit=0",
+		"limit=501",
+	} {
+		query, _ := url.ParseQuery(text)
+		_, _, _, err := parseHistoryQuery(query)
+		if err == nil {
+			t.Errorf("%s accepted", text)
+		}
+	}
+
+	filter, offset, limit, err := parseHistoryQuery(url.Values{})
+	if err != nil || offset != 0 || limit != DefaultSearchLimit || !filter.From.IsZero() {
+		t.Errorf("default query: %+v, %d, %d (%v)", filter, offset, limit, err)
+	}
+}
+
+func TestHistoryAdd(t *testing.T) {
+	index := testIndex(t)
+
+	// A new game goes first, and a game saved again replaces its summary
+	date, _ := time.Parse(time.RFC3339, "2026-01-05T00:00:00Z")
+	index.Add(GameSummary{Id: "e", Map: "tiny", CreatedDate: date, Players: []string{"dan"}})
+
+	date, _ = time.Parse(time.RFC3339, "2026-01-02T10:00:00Z")
+	index.Add(GameSummary{Id: "b", Map: "tiny", CreatedDate: date, Players: []string{"bob", "cid"}, Winners: []string{"bob"}, Turns: 60})
+
+	games, total := index.Search(HistoryFilter{}, 0, 10)
+	if !slices.Equal(ids(games), []string{"e", "d", "c", "b", "a"}) || total != 5 {
+		t.Fatalf("games after adding: %v", ids(games))
+	}
+	if games[3].Turns != 60 || !slices.Equal(games[3].Winners, []string{"bob"}) {
+		t.Errorf("summary not replaced: %+v", games[3])
+	}
+}
+
+func TestPersistIndexesGame(t *testing.T) {
+	session := testSession(t, 2, 3)
+	session.AddPlayer("ann", nil)
+	session.AddPlayer("bob", nil)
+	waitUntilOver(t, session)
+
+	games, total := PastGames.Search(HistoryFilter{Player: "ann"}, 0, 10)
+	if total != 1 || games[0].Id != session.ID || games[0].Turns != 3 || games[0].File != session.historyFile() {
+		t.Errorf("finished game not indexed: %+v", games)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
 	"runtime/debug"
 )
 
@@ -22,8 +23,30 @@ var DevMode bool
 func main() {
 	port := flag.Int("p", 8000, "port on which the server will listen")
 	flag.BoolVar(&DevMode, "dev", false, "run the server in development mode")
+	teamsPath := flag.String("teams", "", "file of the registered teams, who join games with their API key")
+	addTeam := flag.String("addteam", "", "register a team in the teams file, print its API key and exit")
 	flag.Parse()
 
+	if *teamsPath != "" {
+		teams, err := LoadTeams(*teamsPath)
+		if err != nil {
+			log.Fatalf("Could not load teams: %s", err)
+		}
+		RegisteredTeams = teams
+	}
+
+	if *addTeam != "" {
+		if RegisteredTeams == nil {
+			log.Fatalf("No teams file given")
+		}
+		key, err := RegisteredTeams.Add(*addTeam)
+		if err != nil {
+			log.Fatalf("Could not add team: %s", err)
+		}
+		fmt.Println(key)
+		return
+	}
+
 	fmt.Println("git revision: " + GitRevision())
 	RunServer(*port)
 }
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	. "hive-arena/common"
+)
+
+// Queue holds the agents waiting for a game. A game is formed as soon as
+// enough agents want the same number of players and have a map in common.
+type Queue struct {
+	mutex   sync.Mutex
+	waiting []*queueEntry
+}
+
+type queueEntry struct {
+	Name    string
+	Team    *Team
+	Players int
+	Maps    []string
+	Since   time.Time
+
+	// The request of the agent, cancelled if it leaves the queue
+	ctx   context.Context
+	match chan queueMatch
+}
+
+func (entry *queueEntry) cancelled() bool {
+	return entry.ctx.Err() != nil
+}
+
+type queueMatch struct {
+	Game   *GameSession
+	Player *Player
+}
+
+// findMatch looks for agents to play with a new entry, first come first
+// served, on one of the maps it wants. The entry itself comes last.
+func (queue *Queue) findMatch(entry *queueEntry) (string, []*queueEntry) {
+	maps := slices.Clone(entry.Maps)
+	rand.Shuffle(len(maps), func(i, j int) {
+		maps[i], maps[j] = maps[j], maps[i]
+	})
+
+	for _, mapname := range maps {
+		var group []*queueEntry
+		for _, other := range queue.waiting {
+			if len(group) == entry.Players-1 {
+				break
+			}
+			if other.Players == entry.Players && slices.Contains(other.Maps, mapname) {
+				group = append(group, other)
+			}
+		}
+
+		if len(group) == entry.Players-1 {
+			return mapname, append(group, entry)
+		}
+	}
+
+	return "", nil
+}
+
+func (queue *Queue) remove(entries ...*queueEntry) bool {
+	found := false
+	queue.waiting = slices.DeleteFunc(queue.waiting, func(e *queueEntry) bool {
+		if slices.Contains(entries, e) {
+			found = true
+			return true
+		}
+		return false
+	})
+	return found
+}
+
+func (queue *Queue) Status() []QueueStatus {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	statuses := []QueueStatus{}
+	for _, entry := range queue.waiting {
+		statuses = append(statuses, QueueStatus{
+			Name:    entry.Name,
+			Players: entry.Players,
+			Maps:    entry.Maps,
+			Since:   entry.Since,
+		})
+	}
+	return statuses
+}
+
+// enqueue adds an agent to the queue, and starts a game if it completes a
+// group
+func (server *Server) enqueue(entry *queueEntry) {
+	queue := &server.Queue
+
+	queue.mutex.Lock()
+	queue.waiting = slices.DeleteFunc(queue.waiting, (*queueEntry).cancelled)
+	mapname, group := queue.findMatch(entry)
+	if group == nil {
+		queue.waiting = append(queue.waiting, entry)
+		queue.mutex.Unlock()
+		log.Printf("Agent %s is waiting for a %d player game", entry.Name, entry.Players)
+		return
+	}
+	queue.remove(group...)
+	queue.mutex.Unlock()
+
+	// Agents may have left since: the others wait for another group
+	if slices.ContainsFunc(group, (*queueEntry).cancelled) {
+		for _, e := range group {
+			if !e.cancelled() {
+				server.enqueue(e)
+			}
+		}
+		return
+	}
+
+	game := server.createGame(entry.Players, mapname, server.Maps[mapname], DefaultTiming())
+
+	for _, e := range group {
+		player := game.AddPlayer(e.Name, e.Team)
+		if player == nil {
+			log.Printf("Agent %s could not join game %s from the queue", e.Name, game.ID)
+			e.match <- queueMatch{}
+			continue
+		}
+
+		log.Printf("Player %s joined game %s from the queue (#%d, %s)", player.Name, game.ID, player.ID, player.Token)
+		e.match <- queueMatch{game, player}
+	}
+}
+
+// parseQueueEntry checks the number of players and the maps wanted by an
+// agent. No maps means any map for that number of players.
+func (server *Server) parseQueueEntry(ctx context.Context, name string, team *Team, playerStr string, mapsStr string) (*queueEntry, error) {
+	players, err := strconv.Atoi(playerStr)
+	if err != nil || !IsValidNumPlayers(players) {
+		return nil, fmt.Errorf("Invalid number of players: %s", playerStr)
+	}
+
+	var maps []string
+	if mapsStr == "" {
+		for mapname, mapdata := range server.Maps {
+			if mapdata.SupportsNumPlayers(players) {
+				maps = append(maps, mapname)
+			}
+		}
+		slices.Sort(maps)
+	} else {
+		for _, mapname := range strings.Split(mapsStr, ",") {
+			mapdata, found := server.Maps[mapname]
+			if !found {
+				return nil, fmt.Errorf("Map not found: %s", mapname)
+			}
+			if !mapdata.SupportsNumPlayers(players) {
+				return nil, fmt.Errorf("Map %s does not support %d players", mapname, players)
+			}
+			maps = append(maps, mapname)
+		}
+	}
+
+	if len(maps) == 0 {
+		return nil, fmt.Errorf("No map supports %d players", players)
+	}
+
+	return &queueEntry{
+		Name:    name,
+		Team:    team,
+		Players: players,
+		Maps:    maps,
+		Since:   time.Now(),
+		ctx:     ctx,
+		match:   make(chan queueMatch, 1),
+	}, nil
+}
+
+func (server *Server) handleQueue(w http.ResponseWriter, r *http.Request) {
+	logRoute(r)
+
+	query := r.URL.Query()
+	name, team, ok := authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	entry, err := server.parseQueueEntry(r.Context(), name, team, query.Get("players"), query.Get("maps"))
+	if err != nil {
+		writeJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	server.enqueue(entry)
+
+	select {
+	case match := <-entry.match:
+		if match.Player == nil {
+			writeJson(w, "Could not join the game", http.StatusInternalServerError)
+			return
+		}
+
+		writeJson(w, map[string]any{
+			"game":  match.Game.ID,
+			"map":   match.Game.Map,
+			"id":    match.Player.ID,
+			"token": match.Player.Token,
+		}, http.StatusOK)
+
+	case <-r.Context().Done():
+		server.Queue.mutex.Lock()
+		removed := server.Queue.remove(entry)
+		server.Queue.mutex.Unlock()
+
+		if removed {
+			log.Printf("Agent %s left the queue", entry.Name)
+		} else {
+			log.Printf("Agent %s left the queue after being matched", entry.Name)
+		}
+	}
+}
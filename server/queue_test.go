@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	. "hive-arena/common"
+)
+
+func testServer(t *testing.T) *Server {
+	mapData, err := LoadMap("../maps/balanced.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{
+		Maps:     map[string]MapData{"balanced": mapData},
+		Sessions: make(map[string]*GameSession),
+	}
+}
+
+func queueAgent(t *testing.T, server *Server, ctx context.Context, name string) *queueEntry {
+	entry, err := server.parseQueueEntry(ctx, name, nil, "2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.enqueue(entry)
+	return entry
+}
+
+func TestQueueMatch(t *testing.T) {
+	server := testServer(t)
+
+	a := queueAgent(t, server, context.Background(), "a")
+	b := queueAgent(t, server, context.Background(), "b")
+
+	matchA, matchB := <-a.match, <-b.match
+	if matchA.Game == nil || matchA.Game != matchB.Game || matchA.Player.ID == matchB.Player.ID {
+		t.Fatalf("agents were not seated in the same game: %v %v", matchA, matchB)
+	}
+	matchA.Game.Close()
+
+	if len(server.Queue.Status()) != 0 {
+		t.Errorf("matched agents left in the queue")
+	}
+}
+
+func TestQueueDropsCancelledAgents(t *testing.T) {
+	server := testServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	queueAgent(t, server, ctx, "gone")
+	cancel()
+
+	b := queueAgent(t, server, context.Background(), "b")
+	select {
+	case match := <-b.match:
+		t.Fatalf("matched with an agent who left: %v", match)
+	default:
+	}
+
+	status := server.Queue.Status()
+	if len(status) != 1 || status[0].Name != "b" {
+		t.Errorf("unexpected queue: %v", status)
+	}
+
+	c := queueAgent(t, server, context.Background(), "c")
+	matchB, matchC := <-b.match, <-c.match
+	if matchB.Game == nil || matchB.Game != matchC.Game {
+		t.Fatalf("agents were not seated in the same game: %v %v", matchB, matchC)
+	}
+	matchB.Game.Close()
+}
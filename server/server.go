@@ -6,7 +6,9 @@ import (
 	"log"
 	"maps"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"slices"
 	"strconv"
 	"strings"
@@ -27,36 +29,59 @@ type Server struct {
 
 	Maps     map[string]MapData
 	Sessions map[string]*GameSession
+	Queue    Queue
 }
 
-func loadMaps() map[string]MapData {
+// loadMaps reads all the maps of a directory, named after their files without
+// the extension. Two files giving the same name, such as x.txt and x.json, are
+// an error.
+func loadMaps(dir string) (map[string]MapData, error) {
 
 	data := make(map[string]MapData)
+	files := make(map[string]string)
 
-	entries, err := os.ReadDir(MapDir)
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		log.Fatalf("Could not find maps directory")
+		return nil, fmt.Errorf("Could not find maps directory")
 	}
 
 	for _, entry := range entries {
-		name := entry.Name()
-		path := MapDir + "/" + name
-		mapdata, err := LoadMap(path)
+		file := entry.Name()
+		mapdata, err := LoadMap(filepath.Join(dir, file))
 		if err != nil {
-			log.Fatalf("Could not load map %s: %s", name, err)
+			return nil, fmt.Errorf("Could not load map %s: %s", file, err)
 		}
 
-		name = strings.ReplaceAll(name, ".txt", "")
+		name := strings.TrimSuffix(file, filepath.Ext(file))
+		if other, found := files[name]; found {
+			return nil, fmt.Errorf("Duplicate map name %s: %s and %s", name, other, file)
+		}
+		files[name] = file
 		data[name] = mapdata
 	}
 
-	log.Printf("Loaded maps: %s", strings.Join(slices.Collect(maps.Keys(data)), ", "))
+	log.Printf("Loaded maps: %s", strings.Join(slices.Sorted(maps.Keys(data)), ", "))
 
-	return data
+	return data, nil
 }
 
+// Query parameters that are secrets, and must not end up in the logs
+var secretParams = []string{"token", "key"}
+
 func logRoute(r *http.Request) {
-	log.Printf("%s %v %v", r.Method, r.URL, r.RemoteAddr)
+	query := r.URL.Query()
+	for _, param := range secretParams {
+		if query.Has(param) {
+			query.Set(param, "REDACTED")
+		}
+	}
+
+	path := r.URL.Path
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	log.Printf("%s %s %v", r.Method, path, r.RemoteAddr)
 }
 
 func writeJson(w http.ResponseWriter, payload any, status int) {
@@ -65,6 +90,12 @@ func writeJson(w http.ResponseWriter, payload any, status int) {
 	json.NewEncoder(w).Encode(payload)
 }
 
+// The parameters of /newgame that are not rules
+var gameParams = []string{
+	"map", "players", "bots", "botLevel",
+	"turnTimeout", "minTurnDuration", "timeBank", "timeIncrement", "maxTimeouts", "maxTurns",
+}
+
 func (server *Server) handleNewGame(w http.ResponseWriter, r *http.Request) {
 
 	logRoute(r)
@@ -83,9 +114,82 @@ func (server *Server) handleNewGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !mapdata.SupportsNumPlayers(players) {
+		writeJson(w, fmt.Sprintf("Map %s does not support %d players", mapname, players), http.StatusBadRequest)
+		return
+	}
+
+	values := make(map[string]string)
+	for key := range r.URL.Query() {
+		if !slices.Contains(gameParams, key) {
+			values[key] = r.URL.Query().Get(key)
+		}
+	}
+
+	timing, maxTurns, err := parseTiming(r.URL.Query())
+	if err != nil {
+		writeJson(w, "Invalid timing: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rules, err := mapdata.Rules.Override(values, mapdata.LockedRules)
+	if err == nil && maxTurns > 0 {
+		if slices.Contains(mapdata.LockedRules, "turnLimit") {
+			err = fmt.Errorf("turnLimit is locked by the map")
+		}
+		rules.TurnLimit = maxTurns
+	}
+	if err == nil {
+		err = mapdata.CheckRules(rules)
+	}
+	if err != nil {
+		writeJson(w, "Invalid rules: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if rules.TurnLimit > MaxTurnsLimit {
+		writeJson(w, fmt.Sprintf("Invalid rules: turnLimit should be at most %d", MaxTurnsLimit), http.StatusBadRequest)
+		return
+	}
+	mapdata.Rules = rules
+
+	botsStr := r.URL.Query().Get("bots")
+	bots := 0
+	if botsStr != "" {
+		bots, err = strconv.Atoi(botsStr)
+		if err != nil || bots < 0 || bots > players {
+			writeJson(w, fmt.Sprintf("Invalid number of bots: %s", botsStr), http.StatusBadRequest)
+			return
+		}
+	}
+
+	botLevel := r.URL.Query().Get("botLevel")
+	if botLevel == "" {
+		botLevel = DefaultBot
+	}
+	if _, ok := Bots[botLevel]; !ok {
+		writeJson(w, "Invalid bot level: "+botLevel, http.StatusBadRequest)
+		return
+	}
+
+	game := server.createGame(players, mapname, mapdata, timing)
+
+	for range bots {
+		game.AddBot(botLevel)
+	}
+
+	writeJson(w, map[string]any{
+		"id":          game.ID,
+		"numPlayers":  game.NumPlayers,
+		"map":         game.Map,
+		"createdDate": game.CreatedDate,
+		"adminToken":  game.AdminToken,
+	}, http.StatusOK)
+}
+
+func (server *Server) createGame(players int, mapname string, mapdata MapData, timing Timing) *GameSession {
 	server.mutex.Lock()
 	id := GenerateUniqueID(server.Sessions)
-	game := NewGameSession(id, players, mapname, mapdata)
+	game := NewGameSession(id, players, mapname, mapdata, timing)
 	server.Sessions[id] = game
 	server.mutex.Unlock()
 
@@ -94,13 +198,66 @@ func (server *Server) handleNewGame(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Created game %s (%s, %d players)", id, mapname, players)
 
-	writeJson(w, map[string]any{
-		"id":          game.ID,
-		"numPlayers":  game.State.NumPlayers,
-		"map":         game.Map,
-		"createdDate": game.CreatedDate,
-		"adminToken":  game.AdminToken,
-	}, http.StatusOK)
+	return game
+}
+
+// parseTiming reads the optional turnTimeout, minTurnDuration, timeBank and
+// timeIncrement parameters, in milliseconds, maxTimeouts, and maxTurns (0 when
+// not given)
+func parseTiming(query url.Values) (Timing, uint, error) {
+	timing := DefaultTiming()
+
+	duration := func(name string, value *time.Duration, least, most time.Duration) error {
+		text := query.Get(name)
+		if text == "" {
+			return nil
+		}
+		ms, err := strconv.Atoi(text)
+		if err != nil || ms < 0 {
+			return fmt.Errorf("invalid value for %s: %s", name, text)
+		}
+		*value = time.Duration(ms) * time.Millisecond
+		if *value < least || *value > most {
+			return fmt.Errorf("%s should be between %d and %d", name, least.Milliseconds(), most.Milliseconds())
+		}
+		return nil
+	}
+
+	err := duration("turnTimeout", &timing.TurnTimeout, MinTurnTimeoutLimit, MaxTurnTimeoutLimit)
+	if err != nil {
+		return timing, 0, err
+	}
+	err = duration("minTurnDuration", &timing.MinTurnDuration, 0, MaxMinTurnDurationLimit)
+	if err != nil {
+		return timing, 0, err
+	}
+	err = duration("timeBank", &timing.TimeBank, 0, MaxTimeBankLimit)
+	if err != nil {
+		return timing, 0, err
+	}
+	err = duration("timeIncrement", &timing.TimeIncrement, 0, MaxTurnTimeoutLimit)
+	if err != nil {
+		return timing, 0, err
+	}
+
+	if text := query.Get("maxTimeouts"); text != "" {
+		n, err := strconv.Atoi(text)
+		if err != nil || n < 0 {
+			return timing, 0, fmt.Errorf("invalid value for maxTimeouts: %s", text)
+		}
+		timing.MaxTimeouts = n
+	}
+
+	var maxTurns uint
+	if text := query.Get("maxTurns"); text != "" {
+		n, err := strconv.ParseUint(text, 10, 32)
+		if err != nil || n == 0 || n > MaxTurnsLimit {
+			return timing, 0, fmt.Errorf("maxTurns should be between 1 and %d", MaxTurnsLimit)
+		}
+		maxTurns = uint(n)
+	}
+
+	return timing, maxTurns, nil
 }
 
 func (server *Server) removeIfNotStarted(id string) {
@@ -108,8 +265,9 @@ func (server *Server) removeIfNotStarted(id string) {
 	defer server.mutex.Unlock()
 
 	game := server.Sessions[id]
-	if game != nil && !game.IsFull() {
+	if game != nil && !game.HasStarted() {
 		delete(server.Sessions, id)
+		game.Close()
 		log.Printf("Removed game %s because of timeout", id)
 	}
 }
@@ -119,8 +277,9 @@ func (server *Server) removeIfOver(id string) {
 	defer server.mutex.Unlock()
 
 	game := server.Sessions[id]
-	if game != nil && game.State.GameOver {
+	if game != nil && game.IsOver() {
 		delete(server.Sessions, id)
+		game.Close()
 		return
 	}
 
@@ -137,22 +296,54 @@ func (server *Server) getGameSync(id string) *GameSession {
 func (server *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
 	logRoute(r)
 
+	// Sessions answer from their own event loop, which may be busy: do not
+	// hold up the other routes meanwhile
 	server.mutex.Lock()
-	defer server.mutex.Unlock()
+	sessions := slices.Collect(maps.Values(server.Sessions))
+	server.mutex.Unlock()
 
 	var statuses = []SessionStatus{}
-	for _, session := range server.Sessions {
+	for _, session := range sessions {
 		statuses = append(statuses, session.Status())
 	}
 
 	response := StatusResponse{
 		GitRevision: GitRevision(),
 		Games:       statuses,
+		Queue:       server.Queue.Status(),
 	}
 
 	writeJson(w, response, http.StatusOK)
 }
 
+func (server *Server) handleMaps(w http.ResponseWriter, r *http.Request) {
+	logRoute(r)
+
+	var infos = []MapInfo{}
+	for _, name := range slices.Sorted(maps.Keys(server.Maps)) {
+		mapdata := server.Maps[name]
+
+		var players []int
+		for n := 1; n <= MAX_PLAYERS; n++ {
+			if mapdata.SupportsNumPlayers(n) {
+				players = append(players, n)
+			}
+		}
+
+		infos = append(infos, MapInfo{
+			Id:          name,
+			Name:        mapdata.Name,
+			Author:      mapdata.Author,
+			Description: mapdata.Description,
+			Players:     players,
+			Rules:       mapdata.Rules,
+			LockedRules: mapdata.LockedRules,
+		})
+	}
+
+	writeJson(w, infos, http.StatusOK)
+}
+
 func (server *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
 	logRoute(r)
 
@@ -163,13 +354,12 @@ func (server *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	name := r.URL.Query().Get("name")
-	if name == "" {
-		writeJson(w, "Invalid name", http.StatusBadRequest)
+	name, team, ok := authenticate(w, r)
+	if !ok {
 		return
 	}
 
-	player := game.AddPlayer(name)
+	player := game.AddPlayer(name, team)
 	if player == nil {
 		writeJson(w, "Game is full", http.StatusBadRequest)
 		return
@@ -177,7 +367,7 @@ func (server *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Player %s joined game %s (#%d, %s)", player.Name, game.ID, player.ID, player.Token)
 
-	if game.IsFull() {
+	if game.HasStarted() {
 		log.Printf("Game %s has started", id)
 	}
 
@@ -187,6 +377,56 @@ func (server *Server) handleJoin(w http.ResponseWriter, r *http.Request) {
 	}, http.StatusOK)
 }
 
+// authenticate gives the name of an agent joining a game. On servers with
+// registered teams, agents give the API key of their team instead of a name,
+// in the X-Api-Key header. The error response is sent if neither is valid.
+func authenticate(w http.ResponseWriter, r *http.Request) (string, *Team, bool) {
+	if RegisteredTeams == nil {
+		name := r.URL.Query().Get("name")
+		if name == "" {
+			writeJson(w, "Invalid name", http.StatusBadRequest)
+			return "", nil, false
+		}
+		return name, nil, true
+	}
+
+	team := RegisteredTeams.Authenticate(r.Header.Get("X-Api-Key"))
+	if team == nil {
+		writeJson(w, "Invalid key", http.StatusForbidden)
+		return "", nil, false
+	}
+	return team.Name, team, true
+}
+
+func (server *Server) handleTeams(w http.ResponseWriter, r *http.Request) {
+	logRoute(r)
+
+	if RegisteredTeams == nil {
+		writeJson(w, "This server has no registered teams", http.StatusNotFound)
+		return
+	}
+
+	writeJson(w, RegisteredTeams.Ranking(), http.StatusOK)
+}
+
+func (server *Server) handleTeam(w http.ResponseWriter, r *http.Request) {
+	logRoute(r)
+
+	if RegisteredTeams == nil {
+		writeJson(w, "This server has no registered teams", http.StatusNotFound)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	history, found := RegisteredTeams.History(name)
+	if !found {
+		writeJson(w, "Team not found: "+name, http.StatusBadRequest)
+		return
+	}
+
+	writeJson(w, history, http.StatusOK)
+}
+
 func (server *Server) handleGame(w http.ResponseWriter, r *http.Request) {
 	logRoute(r)
 
@@ -197,24 +437,37 @@ func (server *Server) handleGame(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !game.IsFull() {
+	if !game.HasStarted() {
 		writeJson(w, "Game has not started", http.StatusBadRequest)
 		return
 	}
 
 	token := r.URL.Query().Get("token")
-	if token == game.AdminToken {
-		writeJson(w, game.State, http.StatusOK)
+	data, etag := game.GetSnapshot(token)
+	if data == nil {
+		writeJson(w, "Invalid token", http.StatusForbidden)
 		return
 	}
 
-	view := game.GetView(token)
-	if view == nil {
-		writeJson(w, "Invalid token", http.StatusForbidden)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 
-	writeJson(w, view, http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+func etagMatches(header string, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
 }
 
 func (server *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
@@ -227,19 +480,14 @@ func (server *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !game.IsFull() {
+	if !game.HasStarted() {
 		writeJson(w, "Game has not started", http.StatusBadRequest)
 		return
 	}
 
-	if game.State.GameOver {
-		writeJson(w, "Game is over", http.StatusBadRequest)
-		return
-	}
-
 	token := r.URL.Query().Get("token")
-	player := game.Player(token)
-	if player == nil {
+	playerid := game.PlayerID(token)
+	if playerid < 0 {
 		writeJson(w, "Invalid token", http.StatusForbidden)
 		return
 	}
@@ -251,10 +499,87 @@ func (server *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	game.SetOrders(player.ID, orders)
+	err = game.SetOrders(playerid, orders)
+	if err != nil {
+		writeJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	writeJson(w, "OK", http.StatusOK)
 }
 
+func (server *Server) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	logRoute(r)
+
+	id := r.URL.Query().Get("id")
+	game := server.getGameSync(id)
+	if game == nil {
+		writeJson(w, "Invalid game id: "+id, http.StatusBadRequest)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token != game.AdminToken {
+		writeJson(w, "Invalid token", http.StatusForbidden)
+		return
+	}
+
+	player := -1
+	action := AdminAction(r.PathValue("action"))
+	if action == KICK {
+		playerStr := r.URL.Query().Get("player")
+		var err error
+		player, err = strconv.Atoi(playerStr)
+		if err != nil {
+			writeJson(w, "Invalid player: "+playerStr, http.StatusBadRequest)
+			return
+		}
+	}
+
+	err := game.Admin(action, player)
+	if err != nil {
+		writeJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJson(w, "OK", http.StatusOK)
+}
+
+func (server *Server) handleAddBot(w http.ResponseWriter, r *http.Request) {
+	logRoute(r)
+
+	id := r.URL.Query().Get("id")
+	game := server.getGameSync(id)
+	if game == nil {
+		writeJson(w, "Invalid game id: "+id, http.StatusBadRequest)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	if token != game.AdminToken {
+		writeJson(w, "Invalid token", http.StatusForbidden)
+		return
+	}
+
+	level := r.URL.Query().Get("level")
+	if level == "" {
+		level = DefaultBot
+	}
+
+	player, err := game.AddBot(level)
+	if err != nil {
+		writeJson(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("Bot %s joined game %s (#%d)", player.Name, game.ID, player.ID)
+
+	writeJson(w, map[string]any{
+		"id":   player.ID,
+		"name": player.Name,
+	}, http.StatusOK)
+}
+
 func (server *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	logRoute(r)
 
@@ -277,23 +602,37 @@ func (server *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 
 func RunServer(port int) {
 
+	mapdata, err := loadMaps(MapDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	server := Server{
-		Maps:     loadMaps(),
+		Maps:     mapdata,
 		Sessions: make(map[string]*GameSession),
 	}
 
+	PastGames = LoadHistoryIndex(HistoryDir)
+
 	http.HandleFunc("GET /newgame", server.handleNewGame)
 	http.HandleFunc("GET /status", server.handleStatus)
+	http.HandleFunc("GET /maps", server.handleMaps)
 	http.HandleFunc("GET /join", server.handleJoin)
+	http.HandleFunc("GET /queue", server.handleQueue)
+	http.HandleFunc("GET /teams", server.handleTeams)
+	http.HandleFunc("GET /team", server.handleTeam)
 	http.HandleFunc("GET /game", server.handleGame)
 	http.HandleFunc("POST /orders", server.handleOrders)
 	http.HandleFunc("GET /ws", server.handleWebSocket)
+	http.HandleFunc("POST /admin/{action}", server.handleAdmin)
+	http.HandleFunc("POST /admin/addbot", server.handleAddBot)
 
 	fs := http.FileServer(http.Dir("./" + HistoryDir + "/"))
 	http.Handle("GET /history/", http.StripPrefix("/history/", fs))
+	http.HandleFunc("GET /history/search", server.handleHistorySearch)
 
 	log.Printf("Listening on port %d", port)
 
-	err := http.ListenAndServe(":"+strconv.Itoa(port), nil)
+	err = http.ListenAndServe(":"+strconv.Itoa(port), nil)
 	fmt.Println(err)
 }
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newGame(t *testing.T, server *Server, query string) (int, string) {
+	w := httptest.NewRecorder()
+	server.handleNewGame(w, httptest.NewRequest("GET", "/newgame?"+query, nil))
+
+	var response struct {
+		Id string `json:"id"`
+	}
+	if w.Code == http.StatusOK {
+		json.Unmarshal(w.Body.Bytes(), &response)
+		game := server.Sessions[response.Id]
+		t.Cleanup(game.Close)
+	}
+	return w.Code, response.Id
+}
+
+func TestNewGameRules(t *testing.T) {
+	server := testServer(t)
+	locked := server.Maps["balanced"]
+	locked.LockedRules = []string{"beeCost", "turnLimit"}
+	server.Maps["locked"] = locked
+
+	tests := []struct {
+		query  string
+		status int
+	}{
+		{"map=balanced&players=2&beeCost=3&turnTimeout=100&maxTurns=50&bots=1&botLevel=greedy", http.StatusOK},
+		{"map=balanced&players=2&beeCosts=3", http.StatusBadRequest},
+		{"map=balanced&players=2&token=abc", http.StatusBadRequest},
+		{"map=locked&players=2&hiveCost=3", http.StatusOK},
+		{"map=locked&players=2&beeCost=3", http.StatusBadRequest},
+		{"map=locked&players=2&maxTurns=50", http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		status, _ := newGame(t, server, test.query)
+		if status != test.status {
+			t.Errorf("%s: status %d, expected %d", test.query, status, test.status)
+		}
+	}
+
+	_, id := newGame(t, server, tests[0].query)
+	rules := server.Sessions[id].State.Rules
+	if rules.BeeCost != 3 || rules.TurnLimit != 50 {
+		t.Errorf("rules not applied: %+v", rules)
+	}
+}
+
+func TestDuplicateMapNames(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"balanced.txt", "tinyduel.json"} {
+		data, err := os.ReadFile("../maps/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(dir, file), data, 0644)
+	}
+
+	maps, err := loadMaps(dir)
+	if err != nil || len(maps) != 2 {
+		t.Fatalf("%d maps loaded: %v", len(maps), err)
+	}
+
+	data, _ := os.ReadFile("../maps/tinyduel.json")
+	os.WriteFile(filepath.Join(dir, "balanced.json"), data, 0644)
+
+	_, err = loadMaps(dir)
+	if err == nil {
+		t.Error("duplicate map name accepted")
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	server := testServer(t)
+	_, id := newGame(t, server, "map=balanced&players=2")
+
+	join := func(query string, key string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/join?id="+id+query, nil)
+		if key != "" {
+			r.Header.Set("X-Api-Key", key)
+		}
+		server.handleJoin(w, r)
+		return w.Code
+	}
+
+	queue := func(query string, key string) int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/queue?players=2"+query, nil)
+		if key != "" {
+			r.Header.Set("X-Api-Key", key)
+		}
+		server.handleQueue(w, r)
+		return w.Code
+	}
+
+	// Without teams, agents give a name
+	if status := join("", ""); status != http.StatusBadRequest {
+		t.Errorf("join without a name: status %d", status)
+	}
+	if status := queue("", ""); status != http.StatusBadRequest {
+		t.Errorf("queue without a name: status %d", status)
+	}
+
+	// With teams, they give a valid key
+	teams, err := LoadTeams(filepath.Join(t.TempDir(), "teams.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := teams.Add("Alpha")
+	RegisteredTeams = teams
+	t.Cleanup(func() { RegisteredTeams = nil })
+
+	if status := join("&name=Alpha", "wrong"); status != http.StatusForbidden {
+		t.Errorf("join with an invalid key: status %d", status)
+	}
+	if status := queue("", ""); status != http.StatusForbidden {
+		t.Errorf("queue without a key: status %d", status)
+	}
+	if status := join("", key); status != http.StatusOK {
+		t.Errorf("join with a valid key: status %d", status)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"cmp"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	. "hive-arena/common"
+)
+
+const InitialRating = 1500
+const RatingK = 32
+
+// The registered teams, or nil when the server runs without team accounts
+var RegisteredTeams *Teams
+
+type Team struct {
+	Name    string     `json:"name"`
+	KeyHash string     `json:"keyHash"`
+	Rating  float64    `json:"rating"`
+	Games   []TeamGame `json:"games"`
+}
+
+// Teams is the set of registered teams, kept in a JSON file. Teams join games
+// with their API key, of which only a hash is stored.
+type Teams struct {
+	mutex sync.Mutex
+	path  string
+	teams []*Team
+
+	// State of the file when last read or written
+	modTime time.Time
+	size    int64
+}
+
+func hashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// LoadTeams reads the teams file, which does not need to exist yet
+func LoadTeams(path string) (*Teams, error) {
+	teams := &Teams{path: path}
+	err := teams.reload()
+	if err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+// reload picks up the teams added to the file since it was last read, for
+// instance with -addteam while the server is running
+func (teams *Teams) reload() error {
+	info, err := os.Stat(teams.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(teams.modTime) && info.Size() == teams.size {
+		return nil
+	}
+
+	data, err := os.ReadFile(teams.path)
+	if err != nil {
+		return err
+	}
+
+	var saved []*Team
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return err
+	}
+
+	for _, team := range saved {
+		if teams.find(team.Name) == nil {
+			teams.teams = append(teams.teams, team)
+		}
+	}
+
+	teams.modTime, teams.size = info.ModTime(), info.Size()
+	return nil
+}
+
+func (teams *Teams) save() error {
+	err := teams.reload()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(teams.teams, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	tmp := teams.path + ".tmp"
+	err = os.WriteFile(tmp, data, 0600)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmp, teams.path)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(teams.path)
+	if err != nil {
+		return err
+	}
+	teams.modTime, teams.size = info.ModTime(), info.Size()
+	return nil
+}
+
+func (teams *Teams) find(name string) *Team {
+	for _, team := range teams.teams {
+		if team.Name == name {
+			return team
+		}
+	}
+	return nil
+}
+
+// Add registers a new team, and gives its API key
+func (teams *Teams) Add(name string) (string, error) {
+	teams.mutex.Lock()
+	defer teams.mutex.Unlock()
+
+	if name == "" {
+		return "", fmt.Errorf("Invalid name")
+	}
+	if teams.find(name) != nil {
+		return "", fmt.Errorf("Team %s already exists", name)
+	}
+
+	key := rand.Text()
+	teams.teams = append(teams.teams, &Team{
+		Name:    name,
+		KeyHash: hashKey(key),
+		Rating:  InitialRating,
+		Games:   []TeamGame{},
+	})
+
+	return key, teams.save()
+}
+
+// Authenticate gives the team with the given API key, or nil
+func (teams *Teams) Authenticate(key string) *Team {
+	teams.mutex.Lock()
+	defer teams.mutex.Unlock()
+
+	hash := []byte(hashKey(key))
+	find := func() *Team {
+		for _, team := range teams.teams {
+			if subtle.ConstantTimeCompare(hash, []byte(team.KeyHash)) == 1 {
+				return team
+			}
+		}
+		return nil
+	}
+
+	team := find()
+	if team == nil && teams.reload() == nil {
+		team = find()
+	}
+	return team
+}
+
+func (team *Team) info() TeamInfo {
+	won := 0
+	for _, game := range team.Games {
+		if game.Won {
+			won++
+		}
+	}
+	return TeamInfo{Name: team.Name, Rating: team.Rating, Played: len(team.Games), Won: won}
+}
+
+// Ranking lists the teams, best rated first
+func (teams *Teams) Ranking() []TeamInfo {
+	teams.mutex.Lock()
+	defer teams.mutex.Unlock()
+
+	infos := []TeamInfo{}
+	for _, team := range teams.teams {
+		infos = append(infos, team.info())
+	}
+	slices.SortStableFunc(infos, func(a, b TeamInfo) int {
+		return cmp.Compare(b.Rating, a.Rating)
+	})
+	return infos
+}
+
+func (teams *Teams) History(name string) (TeamHistory, bool) {
+	teams.mutex.Lock()
+	defer teams.mutex.Unlock()
+
+	team := teams.find(name)
+	if team == nil {
+		return TeamHistory{}, false
+	}
+	return TeamHistory{TeamInfo: team.info(), Games: slices.Clone(team.Games)}, true
+}
+
+// ratingChanges computes the Elo updates of the teams of a finished game,
+// each pair of teams counting as a match between them: winners beat the other
+// players, and the others draw with each other.
+func ratingChanges(ratings []float64, won []bool) []float64 {
+	changes := make([]float64, len(ratings))
+	if len(ratings) < 2 {
+		return changes
+	}
+
+	k := RatingK / float64(len(ratings)-1)
+	for i := range ratings {
+		for j := range ratings {
+			if i == j {
+				continue
+			}
+
+			expected := 1 / (1 + math.Pow(10, (ratings[j]-ratings[i])/400))
+			score := 0.5
+			if won[i] && !won[j] {
+				score = 1
+			} else if won[j] && !won[i] {
+				score = 0
+			}
+
+			changes[i] += k * (score - expected)
+		}
+	}
+
+	return changes
+}
+
+// RecordGame adds a finished game to the history of the teams who played it,
+// and updates their ratings. Aborted games are not rated.
+func (teams *Teams) RecordGame(session *GameSession) error {
+	teams.mutex.Lock()
+	defer teams.mutex.Unlock()
+
+	state := session.State
+
+	// A team holding several seats is only rated once, for its first one
+	var rated []Player
+	var ratings []float64
+	var won []bool
+	for _, player := range session.Players {
+		if player.Team == nil || slices.ContainsFunc(rated, func(p Player) bool { return p.Team == player.Team }) {
+			continue
+		}
+		rated = append(rated, player)
+		ratings = append(ratings, player.Team.Rating)
+		won = append(won, slices.Contains(state.Winners, player.ID))
+	}
+
+	if len(rated) == 0 {
+		return nil
+	}
+
+	changes := make([]float64, len(rated))
+	if state.EndReason != ABORTED {
+		changes = ratingChanges(ratings, won)
+	}
+
+	players := make([]string, len(session.Players))
+	for i, player := range session.Players {
+		players[i] = player.Name
+	}
+
+	for i, player := range rated {
+		player.Team.Rating += changes[i]
+		player.Team.Games = append(player.Team.Games, TeamGame{
+			Id:        session.ID,
+			Map:       session.Map,
+			Date:      session.CreatedDate,
+			History:   session.historyFile(),
+			Players:   players,
+			Player:    player.ID,
+			Won:       won[i],
+			EndReason: state.EndReason,
+			Rating:    player.Team.Rating,
+		})
+	}
+
+	return teams.save()
+}
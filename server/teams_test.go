@@ -0,0 +1,62 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// A team added with -addteam, by another process, while the server runs
+func TestAddTeamWhileRunning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teams.json")
+
+	running, err := LoadTeams(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = running.Add("Alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := LoadTeams(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := other.Add("Beta")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	team := running.Authenticate(key)
+	if team == nil || team.Name != "Beta" {
+		t.Fatalf("new team cannot join: %v", team)
+	}
+
+	// Saving the running server's teams keeps the new one
+	_, err = running.Add("Gamma")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := LoadTeams(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Authenticate(key) == nil || len(reloaded.Ranking()) != 3 {
+		t.Errorf("teams were lost: %v", reloaded.Ranking())
+	}
+}
+
+func TestRatingChanges(t *testing.T) {
+	changes := ratingChanges([]float64{1500, 1500}, []bool{true, false})
+	if changes[0] != 16 || changes[1] != -16 {
+		t.Errorf("unexpected changes: %v", changes)
+	}
+
+	changes = ratingChanges([]float64{1500, 1500, 1500}, []bool{false, false, false})
+	for _, change := range changes {
+		if change != 0 {
+			t.Errorf("draws should not change equal ratings: %v", changes)
+		}
+	}
+}
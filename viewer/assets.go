@@ -54,6 +54,7 @@ func loadImage(data []byte) *ebiten.Image {
 }
 
 var Font *text.GoTextFace
+var SmallFont *text.GoTextFace
 var LineHeight float64
 
 func LoadResources() {
@@ -78,4 +79,9 @@ func LoadResources() {
 		Size:   16,
 	}
 	LineHeight = Font.Size + 2
+
+	SmallFont = &text.GoTextFace{
+		Source: fontSource,
+		Size:   8,
+	}
 }
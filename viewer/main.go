@@ -26,6 +26,11 @@ var PlayerColors = []color.Color{
 	color.RGBA{255, 100, 255, 255},
 }
 
+var HillColor = color.RGBA{255, 220, 150, 255}
+
+const StunnedDim = 0.5
+const HiddenDim = 0.4
+
 const AutoplaySpeed = 10 // ebiten runs 60 ticks per second, so 6 turns per second
 
 type Viewer struct {
@@ -40,6 +45,12 @@ type Viewer struct {
 	// Autoplay
 	Playing   bool
 	PlayTimer int
+
+	HideFlowerCounts bool
+
+	// Visible area of a player
+	ShowVision   bool
+	VisionPlayer int
 }
 
 func (viewer *Viewer) Update() error {
@@ -60,6 +71,22 @@ func (viewer *Viewer) Update() error {
 		viewer.Cy = ty / Dy
 	}
 
+	if inpututil.IsKeyJustPressed(ebiten.KeyF) {
+		viewer.HideFlowerCounts = !viewer.HideFlowerCounts
+	}
+
+	// Cycle through the players' visible areas, then back to the whole map
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		if !viewer.ShowVision {
+			viewer.ShowVision = true
+			viewer.VisionPlayer = 0
+		} else {
+			viewer.VisionPlayer++
+			state := viewer.Game.History[viewer.Turn].State
+			viewer.ShowVision = viewer.VisionPlayer < state.NumPlayers
+		}
+	}
+
 	// Toggle Autoplay with Space
 	if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
 		viewer.Playing = !viewer.Playing
@@ -158,10 +185,25 @@ func (viewer *Viewer) DrawState(screen *ebiten.Image) {
 		return a.Coords.Row - b.Coords.Row
 	})
 
+	var visible map[Coords]bool
+	if viewer.ShowVision {
+		visible = state.VisibleHexes(viewer.VisionPlayer)
+	}
+	hidden := func(coords Coords) bool {
+		return viewer.ShowVision && !visible[coords]
+	}
+
 	for _, hex := range hexes {
 		opt := ebiten.DrawImageOptions{}
 		opt.GeoM = viewer.CoordsToTransform(hex.Coords)
 
+		if hex.Hex.Hill {
+			opt.ColorScale.ScaleWithColor(HillColor)
+		}
+		if hidden(hex.Coords) {
+			opt.ColorScale.Scale(HiddenDim, HiddenDim, HiddenDim, 1)
+		}
+
 		if hex.Hex.Terrain == FIELD && hex.Hex.Resources == 0 {
 			screen.DrawImage(EmptyFieldTile, &opt)
 		} else {
@@ -170,26 +212,72 @@ func (viewer *Viewer) DrawState(screen *ebiten.Image) {
 	}
 
 	for _, hex := range hexes {
-		entity := hex.Hex.Entity
-		if entity == nil {
-			continue
+		if hex.Hex.Flowers > 0 {
+			opt := ebiten.DrawImageOptions{}
+			opt.GeoM = viewer.CoordsToTransform(hex.Coords)
+			screen.DrawImage(FlowerImage, &opt)
 		}
+	}
 
-		opt := ebiten.DrawImageOptions{}
-		opt.GeoM = viewer.CoordsToTransform(hex.Coords)
-		opt.GeoM.Translate(0, -EntityOffset[entity.Type]*viewer.Scale)
-		opt.ColorScale.ScaleWithColor(PlayerColors[entity.Player])
-		screen.DrawImage(EntityTiles[entity.Type], &opt)
+	if !viewer.HideFlowerCounts {
+		viewer.DrawFlowerCounts(screen, hexes)
+	}
 
-		if entity.HasFlower {
-			opt.ColorScale.Reset()
-			screen.DrawImage(FlowerImage, &opt)
+	for _, hex := range hexes {
+		// A wall that a bee stands in is drawn under the bee
+		for _, entity := range []*Entity{hex.Hex.Wall, hex.Hex.Entity} {
+			if entity != nil {
+				viewer.DrawEntity(screen, state, hex.Coords, entity, hidden(hex.Coords))
+			}
 		}
 	}
 
 	viewer.DrawInfo(screen, state)
 }
 
+// DrawEntity draws a hive, a bee or a wall in the color of its player
+func (viewer *Viewer) DrawEntity(screen *ebiten.Image, state *GameState, coords Coords, entity *Entity, hidden bool) {
+	opt := ebiten.DrawImageOptions{}
+	opt.GeoM = viewer.CoordsToTransform(coords)
+	opt.GeoM.Translate(0, -EntityOffset[entity.Type]*viewer.Scale)
+	opt.ColorScale.ScaleWithColor(PlayerColors[entity.Player])
+	if entity.IsStunned(state.Turn) {
+		opt.ColorScale.Scale(StunnedDim, StunnedDim, StunnedDim, 1)
+	}
+	if hidden {
+		opt.ColorScale.Scale(HiddenDim, HiddenDim, HiddenDim, 1)
+	}
+	screen.DrawImage(EntityTiles[entity.Type], &opt)
+
+	if entity.HasFlower {
+		opt.ColorScale.Reset()
+		screen.DrawImage(FlowerImage, &opt)
+	}
+}
+
+// DrawFlowerCounts shows how many flowers are left in each field, and how
+// many are lying on the ground
+func (viewer *Viewer) DrawFlowerCounts(screen *ebiten.Image, hexes []CoordHex) {
+	for _, hex := range hexes {
+		count := ""
+		if hex.Hex.Terrain == FIELD && hex.Hex.Resources > 0 {
+			count = fmt.Sprint(hex.Hex.Resources)
+		}
+		if hex.Hex.Flowers > 0 {
+			count += fmt.Sprintf("+%d", hex.Hex.Flowers)
+		}
+		if count == "" {
+			continue
+		}
+
+		txtOp := &text.DrawOptions{}
+		txtOp.PrimaryAlign = text.AlignCenter
+		txtOp.GeoM.Translate(Dx/2, Dy*1.5)
+		txtOp.GeoM.Concat(viewer.CoordsToTransform(hex.Coords))
+		text.Draw(screen, count, SmallFont, txtOp)
+	}
+}
+
 func (viewer *Viewer) DrawInfo(screen *ebiten.Image, state *GameState) {
 	txtOp := &text.DrawOptions{}
 	txtOp.GeoM.Translate(LineHeight/2, LineHeight/2)
@@ -203,16 +291,35 @@ func (viewer *Viewer) DrawInfo(screen *ebiten.Image, state *GameState) {
 	txtOp.GeoM.Translate(0, LineHeight)
 	text.Draw(screen, fmt.Sprintf("Turn: %d", state.Turn), Font, txtOp)
 
+	if viewer.ShowVision {
+		txtOp.GeoM.Translate(0, LineHeight)
+		text.Draw(screen, fmt.Sprintf("Vision: player %d", viewer.VisionPlayer), Font, txtOp)
+	}
+
 	for i, player := range viewer.Game.Players {
 		txtOp.GeoM.Translate(0, LineHeight)
 		txtOp.ColorScale.Reset()
 		txtOp.ColorScale.ScaleWithColor(PlayerColors[i])
-		text.Draw(screen, fmt.Sprintf("Player %d: %s (%d flowers)", i, player, state.PlayerResources[i]), Font, txtOp)
+		line := fmt.Sprintf("Player %d: %s (%d flowers)", i, player, state.PlayerResources[i])
+		if state.PlayerBees != nil {
+			line += fmt.Sprintf(" (%d bees)", state.PlayerBees[i])
+		}
+		if state.HillPoints != nil {
+			line += fmt.Sprintf(" (%d hill points)", state.HillPoints[i])
+		}
+		if state.IsEliminated(i) {
+			line += " (eliminated)"
+		}
+		text.Draw(screen, line, Font, txtOp)
 	}
 
 	txtOp.ColorScale.Reset()
 	txtOp.GeoM.Translate(0, LineHeight)
-	text.Draw(screen, fmt.Sprintf("Game over: %v", state.GameOver), Font, txtOp)
+	if state.EndReason != "" {
+		text.Draw(screen, fmt.Sprintf("Game over: %v (%s)", state.GameOver, state.EndReason), Font, txtOp)
+	} else {
+		text.Draw(screen, fmt.Sprintf("Game over: %v", state.GameOver), Font, txtOp)
+	}
 
 	if state.GameOver {
 		txtOp.GeoM.Translate(0, LineHeight)